@@ -3,10 +3,14 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -41,11 +45,27 @@ func main() {
 
 	// 解析命令行参数
 	configPath := flag.String("config", "config.yaml", "配置文件路径")
-	excelPath := flag.String("excel", "requirements.xlsx", "Excel文件路径")
-	action := flag.String("action", "import", "操作类型: import(导入)、delete(删除)")
-	productID := flag.Int("product", 0, "产品ID（删除时必填）")
+	excelPath := flag.String("excel", "requirements.xlsx", "需求数据文件路径（Excel、CSV、JSON、YAML或Markdown）")
+	format := flag.String("format", "", "数据文件格式（导入、比对时可选）: excel、csv、json、yaml、markdown，默认使用配置文件中的 format，未配置时按文件扩展名判断")
+	action := flag.String("action", "import", "操作类型: import(导入)、delete(删除)、export(导出)、diff(比对)、rollback(回滚)")
+	productID := flag.Int("product", 0, "产品ID（删除、导出时必填；比对时可选，默认比对Excel涉及的全部产品）")
 	titleFilter := flag.String("title", "", "标题筛选（删除时可选，部分匹配）")
 	openedByFilter := flag.String("openedBy", "", "创建者筛选（删除时可选，精确匹配账号名）")
+	dryRun := flag.Bool("dry-run", false, "演练模式（导入时可选）：校验全部数据并打印将发送的请求，不创建任何需求")
+	resumePath := flag.String("resume", "", "续传（导入时可选）：指定上次导入的检查点日志文件，跳过已创建的行")
+	upsert := flag.Bool("upsert", false, "幂等导入（导入时可选）：按Excel\"外部ID\"列匹配已导入的需求，已存在则更新，否则新建")
+	upsertMapPath := flag.String("upsert-map", zentao.DefaultUpsertMapPath, "幂等导入使用的外部ID映射文件（回滚时会移除已回滚需求的记录）")
+	writeBack := flag.Bool("write-back", false, "导入后将禅道ID、导入状态和错误信息回写到Excel源文件（导入时可选）")
+	outputPath := flag.String("output", "", "输出文件路径：导入时为回写结果另存路径（不修改Excel源文件），导出时为导出文件路径，比对时为差异报表路径")
+	onParentFailure := flag.String("on-parent-failure", "", "父需求导入失败时子需求的处理策略（导入时可选）: skip(跳过后代)、orphan(不设父需求继续创建)、abort(中止导入)，默认使用配置文件中的 onParentFailure，未配置时为 orphan")
+	runID := flag.String("run", "", "回滚时必填：要回滚的导入运行ID（检查点日志文件名，如 20260101-120000-3f9a1c）或检查点日志路径")
+	concurrency := flag.Int("concurrency", 1, "导入时同一层级内的并发数（导入时可选，默认1为顺序导入，最大10）；各层级仍按 Epic → Requirement → Story 依次导入")
+	createModules := flag.Bool("create-modules", false, "导入前自动创建Excel模块列或 defaultModule 中填写的、产品中尚不存在的模块路径（导入时可选）")
+	sheets := flag.String("sheets", "", "要读取的工作表（导入、比对时可选）：all 读取全部工作表，或以逗号分隔的工作表名称；默认使用配置文件中的 sheets，未配置时只读取第一个工作表")
+	errorReport := flag.String("error-report", "", "Excel数据校验失败时，将标红并批注了错误单元格的副本保存到指定路径（导入、比对时可选）")
+	yes := flag.Bool("yes", false, "非交互模式（导入、删除、回滚时可选）：跳过 yes/no 确认，用于CI；导入时须同时指定 -confirm-product，删除、回滚时须同时指定 -confirm-count")
+	confirmProduct := flag.String("confirm-product", "", "预先确认的产品ID，以逗号分隔（导入、删除时可选）：数据文件涉及其他产品、或删除的产品不在其中时中止")
+	confirmCount := flag.Int("confirm-count", 0, "预先确认的需求数量（删除、回滚时可选）：匹配到的需求数量与之不同时中止")
 	flag.Parse()
 
 	// 加载配置文件
@@ -64,31 +84,168 @@ func main() {
 		log.Fatal("%v", err)
 	}
 
+	// 命令行指定的数据格式覆盖配置文件，未指定时按扩展名判断
+	if *format != "" {
+		cfg.Format = *format
+	}
+	if cfg.Format, err = excel.DetectFormat(cfg.ExcelFile, cfg.Format); err != nil {
+		log.Fatal("%v", err)
+	}
+
+	// 命令行指定的工作表覆盖配置文件；配置文件中的工作表只适用于Excel文件，其他格式忽略
+	if applySheets(cfg, *sheets) {
+		log.Info("配置文件中的 sheets 仅适用于Excel文件，当前数据格式为 %s，已忽略", cfg.Format)
+	}
+
+	// 命令行指定的父需求失败策略覆盖配置文件
+	if *onParentFailure != "" {
+		cfg.OnParentFailure = *onParentFailure
+	}
+
+	// 非交互确认选项
+	confirmedProducts, err := parseProductIDs(*confirmProduct)
+	if err != nil {
+		log.Fatal("-confirm-product 参数错误: %v", err)
+	}
+	if *confirmCount < 0 {
+		log.Fatal("-confirm-count 参数错误: 数量不能为负数")
+	}
+	confirm := confirmOptions{yes: *yes, products: confirmedProducts, count: *confirmCount}
+
 	// 根据操作类型执行相应功能
 	switch *action {
 	case "import":
-		handleImport(cfg, log)
+		handleImport(cfg, log, importOptions{
+			dryRun:        *dryRun,
+			resumePath:    *resumePath,
+			writeBack:     *writeBack,
+			outputPath:    *outputPath,
+			upsertMap:     upsertMapOption(*upsert, *upsertMapPath),
+			errorReport:   *errorReport,
+			concurrency:   *concurrency,
+			createModules: *createModules,
+			confirm:       confirm,
+		})
 	case "delete":
-		handleDelete(cfg, log, *productID, *titleFilter, *openedByFilter)
+		handleDelete(cfg, log, *productID, *titleFilter, *openedByFilter, confirm)
+	case "export":
+		handleExport(cfg, log, *productID, *outputPath)
+	case "diff":
+		handleDiff(cfg, log, *productID, *outputPath, *errorReport)
+	case "rollback":
+		handleRollback(cfg, log, *runID, *upsertMapPath, confirm)
 	default:
-		log.Fatal("不支持的操作类型: %s，仅支持 import 或 delete", *action)
+		log.Fatal("不支持的操作类型: %s，仅支持 import、delete、export、diff 或 rollback", *action)
 	}
 }
 
-// handleImport 处理导入操作
-func handleImport(cfg *config.Config, log *logger.Logger) {
-	// 创建Excel读取器
-	reader, err := excel.NewReader(cfg.ExcelFile)
-	if err != nil {
-		log.Fatal("创建Excel读取器失败: %v", err)
+// importOptions 导入操作的命令行选项
+type importOptions struct {
+	dryRun        bool   // 只执行解析和预检，打印每行将发送的请求，不创建任何需求
+	resumePath    string // 续传时使用的检查点日志路径，为空表示新的导入
+	writeBack     bool   // 导入后将结果回写到Excel源文件
+	outputPath    string // 回写结果另存路径（非空时不修改源文件）
+	upsertMap     string // 幂等导入的外部ID映射文件路径，为空表示不启用
+	errorReport   string // 数据校验失败时导出错误标注副本的路径，为空表示不导出
+	concurrency   int    // 同一层级内的并发导入数，<=1 表示顺序导入
+	createModules bool   // 导入前自动创建不存在的模块路径
+	confirm       confirmOptions
+}
+
+// confirmOptions 非交互确认选项，CI等无法输入 "yes" 的环境通过命令行参数预先确认操作范围
+type confirmOptions struct {
+	yes      bool  // 跳过交互确认
+	products []int // 预先确认的产品ID，为空表示未指定
+	count    int   // 预先确认的需求数量，0表示未指定
+}
+
+// parseProductIDs 解析以逗号分隔的产品ID列表
+func parseProductIDs(value string) ([]int, error) {
+	var ids []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("产品ID必须为正整数: %s", part)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
 	}
-	defer reader.Close()
+	return ids, nil
+}
 
-	// 读取需求数据
-	stories, err := reader.ReadStories(cfg.DefaultPriority)
+// checkProducts 校验将操作的产品均已通过 -confirm-product 确认，未指定 -confirm-product 时不校验
+func (c confirmOptions) checkProducts(productIDs []int) error {
+	if len(c.products) == 0 {
+		return nil
+	}
+	var others []string
+	for _, id := range slices.Sorted(slices.Values(productIDs)) {
+		if !slices.Contains(c.products, id) {
+			others = append(others, strconv.Itoa(id))
+		}
+	}
+	if len(others) > 0 {
+		return fmt.Errorf("涉及未经 -confirm-product 确认的产品: %s，操作已取消", strings.Join(others, ", "))
+	}
+	return nil
+}
+
+// checkCount 校验匹配到的需求数量与 -confirm-count 一致，未指定 -confirm-count 时不校验
+func (c confirmOptions) checkCount(count int) error {
+	if c.count > 0 && c.count != count {
+		return fmt.Errorf("匹配到 %d 个需求，与 -confirm-count %d 不一致，操作已取消", count, c.count)
+	}
+	return nil
+}
+
+// ask 打印确认提示并读取用户输入，输入 yes 或 y 时返回true；指定 -yes 时不读取输入，直接确认
+func (c confirmOptions) ask(log *logger.Logger, prompt string) bool {
+	fmt.Print(prompt)
+	if c.yes {
+		fmt.Printf("yes（-yes 非交互确认）\n")
+		return true
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
 	if err != nil {
-		log.Fatal("读取Excel数据失败: %v", err)
+		log.Fatal("读取用户输入失败: %v", err)
+	}
+	input = strings.TrimSpace(strings.ToLower(input))
+	return input == "yes" || input == "y"
+}
+
+// parentFailureDescriptions 父需求失败策略在确认界面中的说明
+var parentFailureDescriptions = map[zentao.ParentFailurePolicy]string{
+	zentao.ParentFailureOrphan: "仍创建其子需求，但不设置父需求 (orphan)",
+	zentao.ParentFailureSkip:   "跳过其全部后代需求 (skip)",
+	zentao.ParentFailureAbort:  "中止导入，剩余需求全部跳过 (abort)",
+}
+
+// upsertMapOption 仅在启用 -upsert 时返回映射文件路径
+func upsertMapOption(enabled bool, path string) string {
+	if !enabled {
+		return ""
 	}
+	return path
+}
+
+// handleImport 处理导入操作
+func handleImport(cfg *config.Config, log *logger.Logger, opts importOptions) {
+	if (opts.writeBack || opts.outputPath != "") && cfg.Format != excel.FormatExcel {
+		log.Fatal("回写导入结果仅支持Excel文件，当前数据文件格式为 %s", cfg.Format)
+	}
+	if opts.confirm.yes && len(opts.confirm.products) == 0 && !opts.dryRun {
+		log.Fatal("非交互导入 (-yes) 必须通过 -confirm-product 指定确认的产品ID")
+	}
+
+	// 读取需求数据
+	stories := readStories(cfg, log, opts.errorReport)
 
 	log.Info("从Excel中读取到 %d 个需求", len(stories))
 
@@ -105,20 +262,110 @@ func handleImport(cfg *config.Config, log *logger.Logger) {
 		}
 	}
 
-	// 提取所有唯一的产品ID
+	// 创建禅道客户端
+	client, err := zentao.NewClient(cfg)
+	if err != nil {
+		log.Fatal("创建禅道客户端失败: %v", err)
+	}
+
+	// 产品列填写名称或代号的行解析为产品ID
+	resolveProducts(client, log, stories)
+
+	// 提取所有唯一的产品ID，及各产品在Excel中填写的名称或代号
 	productIDSet := make(map[int]bool)
+	productRefs := make(map[int][]string)
 	for _, s := range stories {
 		productIDSet[s.ProductID] = true
+		if s.ProductRef != "" && !slices.Contains(productRefs[s.ProductID], s.ProductRef) {
+			productRefs[s.ProductID] = append(productRefs[s.ProductID], s.ProductRef)
+		}
 	}
 	productIDs := make([]int, 0, len(productIDSet))
 	for id := range productIDSet {
 		productIDs = append(productIDs, id)
 	}
+	sort.Ints(productIDs)
 
-	// 创建禅道客户端
-	client, err := zentao.NewClient(cfg)
+	// 数据文件涉及未预先确认的产品时拒绝导入
+	if err := opts.confirm.checkProducts(productIDs); err != nil {
+		log.Fatal("%v", err)
+	}
+
+	parentPolicy, err := zentao.ParseParentFailurePolicy(cfg.OnParentFailure)
 	if err != nil {
-		log.Fatal("创建禅道客户端失败: %v", err)
+		log.Fatal("%v", err)
+	}
+
+	if opts.dryRun {
+		handleDryRun(client, log, stories, cfg.GetDefaultModulePath(), opts.createModules)
+		return
+	}
+
+	// 父需求引用或子需求层级有问题时拒绝导入（演练模式下由预检一并报告）
+	issues := append(story.ValidateReferences(stories), story.ValidateGrades(stories, cfg.GetMaxGrade)...)
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个父需求引用或层级问题，请修正后再导入（可使用 -dry-run 查看完整预检结果）", len(issues))
+	}
+
+	// 评审人/指派给账号或姓名无法解析时拒绝导入
+	preflight := zentao.NewPreflight(client, log)
+	if accountIssues := preflight.CheckAccounts(stories); len(accountIssues) > 0 {
+		for _, issue := range accountIssues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个账号问题，请修正后再导入（可使用 -dry-run 查看完整预检结果）", len(accountIssues))
+	}
+
+	// 模块路径：未启用 -create-modules 时必须全部存在；启用时确认后再创建缺失的模块
+	modules := zentao.NewModuleResolver(client, log)
+	defaultModulePath := cfg.GetDefaultModulePath()
+	var missingModules []zentao.ModulePath
+	if opts.createModules {
+		missingModules = modules.Missing(stories, defaultModulePath)
+	} else if moduleIssues := modules.Resolve(stories, defaultModulePath, false); len(moduleIssues) > 0 {
+		for _, issue := range moduleIssues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个模块路径问题，请修正后再导入（可使用 -create-modules 自动创建缺失的模块）", len(moduleIssues))
+	}
+
+	// 关联的项目/执行/计划：名称解析为ID，并校验与产品的关联关系
+	if linkIssues := zentao.NewProjectResolver(client, log).Resolve(stories); len(linkIssues) > 0 {
+		for _, issue := range linkIssues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个项目/执行/计划问题，请修正后再导入（可使用 -dry-run 查看完整预检结果）", len(linkIssues))
+	}
+
+	// "#title:标题" 父需求：在产品已有的业务需求和用户需求中按标题查找
+	if titleIssues := zentao.NewParentTitleResolver(client, log).Resolve(stories); len(titleIssues) > 0 {
+		for _, issue := range titleIssues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个父需求标题问题，请修正后再导入（标题重复时可改为填写禅道ID）", len(titleIssues))
+	}
+
+	// 续传时加载原检查点日志，并校验Excel未被修改
+	var journal *zentao.Journal
+	if opts.resumePath != "" {
+		if _, err := os.Stat(opts.resumePath); err != nil {
+			log.Fatal("读取检查点日志失败: %v", err)
+		}
+		journal, err = zentao.OpenJournal(opts.resumePath)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		defer journal.Close()
+
+		if mismatches := journal.Mismatches(stories); len(mismatches) > 0 {
+			for _, m := range mismatches {
+				log.Error("%s", m)
+			}
+			log.Fatal("Excel内容与检查点日志不一致，无法续传（请使用原Excel文件，或去掉 -resume 参数重新导入）")
+		}
 	}
 
 	// 获取产品名称信息
@@ -141,9 +388,47 @@ func handleImport(cfg *config.Config, log *logger.Logger) {
 
 	fmt.Printf("导入顺序: Epic → Requirement → Story\n\n")
 
+	if opts.upsertMap != "" {
+		externalCount := 0
+		for _, s := range stories {
+			if s.ExternalID != "" {
+				externalCount++
+			}
+		}
+		fmt.Printf("幂等导入: %d 条需求带外部ID，将按映射文件 %s 判断新建或更新\n\n", externalCount, opts.upsertMap)
+	}
+
+	if opts.resumePath != "" {
+		fmt.Printf("续传模式: 检查点日志 %s 中已创建的 %d 条需求将被跳过\n\n", journal.Path(), journal.CreatedCount())
+	}
+
+	fmt.Printf("父需求导入失败时: %s\n\n", parentFailureDescriptions[parentPolicy])
+
+	linkedCount := 0
+	for _, s := range stories {
+		if s.Project > 0 || s.Execution > 0 || s.Plan > 0 {
+			linkedCount++
+		}
+	}
+	if linkedCount > 0 {
+		fmt.Printf("关联项目/执行/计划: %d 条需求将在创建时直接关联\n\n", linkedCount)
+	}
+
+	if opts.concurrency > 1 {
+		fmt.Printf("并发导入: 同一层级内最多 %d 个需求同时创建\n\n", opts.concurrency)
+	}
+
+	if len(missingModules) > 0 {
+		fmt.Printf("将自动创建以下 %d 个模块路径:\n", len(missingModules))
+		for _, m := range missingModules {
+			fmt.Printf("  %s\n", m)
+		}
+		fmt.Printf("\n")
+	}
+
 	fmt.Printf("涉及产品:\n")
-	fmt.Printf("%-10s %-40s %-10s\n", "产品ID", "产品名称", "需求数量")
-	fmt.Printf("%-10s %-40s %-10s\n", "------", "----------------------------------------", "------")
+	fmt.Printf("%-10s %-40s %-10s %s\n", "产品ID", "产品名称", "需求数量", "Excel中填写")
+	fmt.Printf("%-10s %-40s %-10s %s\n", "------", "----------------------------------------", "------", "----------")
 
 	productCount := make(map[int]int)
 	for _, s := range stories {
@@ -154,30 +439,67 @@ func handleImport(cfg *config.Config, log *logger.Logger) {
 		if productName == "" {
 			productName = "[未知产品]"
 		}
-		fmt.Printf("%-10d %-40s %-10d\n", productID, productName, productCount[productID])
+		excelRef := strings.Join(productRefs[productID], ", ")
+		if excelRef == "" {
+			excelRef = "产品ID"
+		}
+		fmt.Printf("%-10d %-40s %-10d %s\n", productID, productName, productCount[productID], excelRef)
 	}
 
 	fmt.Printf("\n%s\n", separator)
 	fmt.Printf("\n⚠️  重要提示：\n")
-	fmt.Printf("   1. 请仔细核对上述产品信息，错误的产品ID会导致数据导入错误产品\n")
+	fmt.Printf("   1. 请仔细核对上述产品信息，错误的产品ID或产品名称会导致数据导入错误产品\n")
 	fmt.Printf("   2. 父需求引用(@行号)将在导入时自动解析为实际禅道ID\n")
-	fmt.Printf("   3. 导入顺序为 Epic → Requirement → Story，确保父需求先创建\n")
-	fmt.Printf("\n是否确认导入? (yes/no): ")
+	fmt.Printf("   3. 导入顺序为 Epic → Requirement → Story，同类型的子需求在其父需求之后创建\n")
+	if !opts.confirm.ask(log, "\n是否确认导入? (yes/no): ") {
+		log.Info("取消导入操作")
+		return
+	}
 
-	confirmReader := bufio.NewReader(os.Stdin)
-	response, err := confirmReader.ReadString('\n')
-	if err != nil {
-		log.Fatal("读取用户输入失败: %v", err)
+	// 创建缺失的模块后解析模块路径
+	if opts.createModules {
+		if err := modules.CreateMissing(missingModules); err != nil {
+			log.Fatal("%v", err)
+		}
+		if moduleIssues := modules.Resolve(stories, defaultModulePath, false); len(moduleIssues) > 0 {
+			for _, issue := range moduleIssues {
+				log.Error("%s", issue)
+			}
+			log.Fatal("发现 %d 个模块路径问题，导入已取消", len(moduleIssues))
+		}
 	}
 
-	response = strings.TrimSpace(strings.ToLower(response))
-	if response != "yes" && response != "y" {
-		log.Info("取消导入操作")
-		return
+	// 幂等导入：加载外部ID映射
+	var upsertMap *zentao.UpsertMap
+	if opts.upsertMap != "" {
+		upsertMap, err = zentao.LoadUpsertMap(opts.upsertMap)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+	}
+
+	// 新的导入为本次运行创建检查点日志
+	if journal == nil {
+		journal, err = zentao.CreateJournal(zentao.JournalDir)
+		if err != nil {
+			log.Fatal("%v", err)
+		}
+		defer journal.Close()
 	}
 
 	// 创建导入器
 	importer := zentao.NewImporter(client, log)
+	importer.SetJournal(journal)
+	importer.SetParentFailurePolicy(parentPolicy)
+	importer.SetConcurrency(opts.concurrency)
+	if userIndex, err := preflight.UserIndex(); err == nil {
+		importer.SetUserIndex(userIndex)
+	}
+	if upsertMap != nil {
+		importer.SetUpsertMap(upsertMap)
+	}
+	log.Info("检查点日志: %s（导入中断时可使用 -resume %s 续传，撤销本次导入可使用 -action rollback -run %s）",
+		journal.Path(), journal.Path(), journal.RunID())
 
 	// 层级导入
 	results := importer.ImportStories(stories)
@@ -186,6 +508,17 @@ func handleImport(cfg *config.Config, log *logger.Logger) {
 	report := importer.GenerateReport(results)
 	log.Info("\n%s", report)
 
+	// 回写导入结果到Excel
+	if opts.writeBack || opts.outputPath != "" {
+		if err := writeBackResults(cfg.ExcelFile, opts.outputPath, stories, results); err != nil {
+			log.Error("回写Excel失败: %v", err)
+		} else if opts.outputPath != "" {
+			log.Info("导入结果已写入: %s", opts.outputPath)
+		} else {
+			log.Info("导入结果已回写至: %s", cfg.ExcelFile)
+		}
+	}
+
 	log.Info("日志文件已保存至: %s", log.GetLogFilePath())
 
 	hasFailure := false
@@ -199,12 +532,114 @@ func handleImport(cfg *config.Config, log *logger.Logger) {
 	}
 }
 
+// writeBackResults 将导入结果按行号回写到Excel（outputPath 为空时原地保存）
+func writeBackResults(excelPath, outputPath string, stories []story.Story, results []zentao.ImportResult) error {
+	writer, err := excel.NewWriter(excelPath)
+	if err != nil {
+		return err
+	}
+	defer writer.Close()
+
+	rowResults := make([]excel.RowResult, len(results))
+	for idx, result := range results {
+		s := stories[idx]
+		rowResult := excel.RowResult{RowIndex: s.RowIndex, Sheet: s.Sheet, SheetRow: s.SheetRow, ID: result.StoryID}
+		switch {
+		case result.Resumed:
+			rowResult.Status = "成功(续传)"
+		case result.Success:
+			rowResult.Status = "成功"
+		case result.Skipped:
+			rowResult.Status = "跳过"
+			rowResult.Message = result.SkipReason
+		default:
+			rowResult.Status = "失败"
+			if result.Error != nil {
+				rowResult.Message = result.Error.Error()
+			}
+		}
+		rowResults[idx] = rowResult
+	}
+
+	if err := writer.WriteResults(rowResults); err != nil {
+		return err
+	}
+	return writer.Save(outputPath)
+}
+
+// handleDryRun 演练导入：执行预检并打印每行将发送的创建请求，存在问题时以非零状态码退出
+func handleDryRun(client *zentao.Client, log *logger.Logger, stories []story.Story, defaultModulePath string, createModules bool) {
+	// 先将模块路径解析为模块ID，启用 -create-modules 时缺失的路径不视为问题
+	modules := zentao.NewModuleResolver(client, log)
+	var missingModules []zentao.ModulePath
+	if createModules {
+		missingModules = modules.Missing(stories, defaultModulePath)
+	}
+	issues := modules.Resolve(stories, defaultModulePath, createModules)
+	issues = append(issues, zentao.NewProjectResolver(client, log).Resolve(stories)...)
+	issues = append(issues, zentao.NewParentTitleResolver(client, log).Resolve(stories)...)
+
+	preflight := zentao.NewPreflight(client, log)
+	issues = append(issues, preflight.Check(stories)...)
+
+	importer := zentao.NewImporter(client, log)
+	if userIndex, err := preflight.UserIndex(); err == nil {
+		importer.SetUserIndex(userIndex)
+	}
+	plans := importer.PlanStories(stories)
+
+	separator := strings.Repeat("=", 60)
+	fmt.Printf("\n%s\n", separator)
+	fmt.Printf("           导入演练（dry-run）— 不会创建任何数据\n")
+	fmt.Printf("%s\n\n", separator)
+	fmt.Print(zentao.FormatPlan(plans))
+
+	if len(missingModules) > 0 {
+		fmt.Printf("\n将自动创建以下 %d 个模块路径（演练中未创建，相关需求的模块ID显示为0）:\n", len(missingModules))
+		for _, m := range missingModules {
+			fmt.Printf("  %s\n", m)
+		}
+	}
+
+	fmt.Printf("\n%s\n", separator)
+	fmt.Printf("           预检结果\n")
+	fmt.Printf("%s\n\n", separator)
+
+	failedRows := make(map[int]bool)
+	for _, issue := range issues {
+		fmt.Printf("✗ %s\n", issue)
+		if issue.RowIndex > 0 {
+			failedRows[issue.RowIndex] = true
+		}
+	}
+
+	fmt.Printf("\n总计统计:\n")
+	fmt.Printf("- 总需求数: %d\n", len(stories))
+	fmt.Printf("- 通过预检: %d\n", len(stories)-len(failedRows))
+	fmt.Printf("- 存在问题: %d 行，共 %d 个问题\n", len(failedRows), len(issues))
+
+	log.Info("日志文件已保存至: %s", log.GetLogFilePath())
+
+	if len(issues) > 0 {
+		fmt.Printf("\n演练结果: 失败，请修正以上问题后再导入\n")
+		os.Exit(1)
+	}
+	fmt.Printf("\n演练结果: 通过，可去掉 -dry-run 参数执行实际导入\n")
+}
+
 // handleDelete 处理删除操作
 // 必须指定产品ID，支持标题（部分匹配）和创建者作为可选过滤条件
-func handleDelete(cfg *config.Config, log *logger.Logger, productID int, titleFilter, openedByFilter string) {
+// 非交互删除 (-yes) 必须通过 -confirm-count 确认将删除的数量
+func handleDelete(cfg *config.Config, log *logger.Logger, productID int, titleFilter, openedByFilter string, confirm confirmOptions) {
 	if productID <= 0 {
 		log.Fatal("删除操作必须指定产品ID (-product 参数)")
 	}
+	if confirm.yes && confirm.count == 0 {
+		log.Fatal("非交互删除 (-yes) 必须通过 -confirm-count 指定确认删除的需求数量")
+	}
+	if err := confirm.checkProducts([]int{productID}); err != nil {
+		log.Fatal("%v", err)
+	}
 
 	separator := strings.Repeat("=", 60)
 
@@ -250,6 +685,11 @@ func handleDelete(cfg *config.Config, log *logger.Logger, productID int, titleFi
 	}
 	matchedItems := deleter.FetchByFilter(filter)
 
+	// 匹配数量与预先确认的数量不同时中止（筛选条件有误或产品数据已变化）
+	if err := confirm.checkCount(len(matchedItems)); err != nil {
+		log.Fatal("%v", err)
+	}
+
 	if len(matchedItems) == 0 {
 		fmt.Printf("\n未找到匹配的需求。\n")
 		log.Info("未找到匹配的需求，产品ID=%d，标题=%s，创建者=%s", productID, titleFilter, openedByFilter)
@@ -274,16 +714,7 @@ func handleDelete(cfg *config.Config, log *logger.Logger, productID int, titleFi
 	}
 	fmt.Printf("   筛选条件: %s\n", strings.Join(conditions, ", "))
 	fmt.Printf("   此操作不可撤销！\n")
-	fmt.Printf("\n请输入 \"yes\" 确认删除: ")
-
-	reader := bufio.NewReader(os.Stdin)
-	confirmInput, err := reader.ReadString('\n')
-	if err != nil {
-		log.Fatal("读取用户输入失败: %v", err)
-	}
-
-	confirmInput = strings.TrimSpace(strings.ToLower(confirmInput))
-	if confirmInput != "yes" && confirmInput != "y" {
+	if !confirm.ask(log, "\n请输入 \"yes\" 确认删除: ") {
 		log.Info("取消删除操作")
 		return
 	}
@@ -314,6 +745,335 @@ func handleDelete(cfg *config.Config, log *logger.Logger, productID int, titleFi
 	}
 }
 
+// handleExport 处理导出操作
+// 将产品的需求树按导入模板格式导出为Excel，父需求以@行号引用，可编辑后重新导入
+func handleExport(cfg *config.Config, log *logger.Logger, productID int, outputPath string) {
+	if productID <= 0 {
+		log.Fatal("导出操作必须指定产品ID (-product 参数)")
+	}
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("product_%d_stories.xlsx", productID)
+	}
+
+	client, err := zentao.NewClient(cfg)
+	if err != nil {
+		log.Fatal("创建禅道客户端失败: %v", err)
+	}
+
+	log.Info("正在获取产品 %d 的需求...", productID)
+	exporter := zentao.NewExporter(client, log)
+	stories, err := exporter.Export(productID)
+	if err != nil {
+		log.Fatal("导出失败: %v", err)
+	}
+
+	if err := excel.ExportStories(outputPath, stories); err != nil {
+		log.Fatal("写入Excel失败: %v", err)
+	}
+
+	epicCount, reqCount, storyCount := 0, 0, 0
+	for _, s := range stories {
+		switch s.Type {
+		case story.StoryTypeEpic:
+			epicCount++
+		case story.StoryTypeRequirement:
+			reqCount++
+		case story.StoryTypeStory:
+			storyCount++
+		}
+	}
+	log.Info("导出完成: 业务需求 %d 条，用户需求 %d 条，研发需求 %d 条，共 %d 条", epicCount, reqCount, storyCount, len(stories))
+	log.Info("导出文件已保存至: %s", outputPath)
+}
+
+// handleRollback 处理回滚操作
+// 按检查点日志删除指定运行中创建的需求（Story → Requirement → Epic），不影响其他需求
+// 回滚同样是删除操作，非交互回滚 (-yes) 必须通过 -confirm-count 确认将删除的数量
+func handleRollback(cfg *config.Config, log *logger.Logger, run, upsertMapPath string, confirm confirmOptions) {
+	if run == "" {
+		log.Fatal("回滚操作必须指定运行ID (-run 参数)，可在 %s 目录中查看", zentao.JournalDir)
+	}
+	if confirm.yes && confirm.count == 0 {
+		log.Fatal("非交互回滚 (-yes) 必须通过 -confirm-count 指定确认删除的需求数量")
+	}
+
+	path := zentao.RunJournalPath(run)
+	if _, err := os.Stat(path); err != nil {
+		log.Fatal("读取检查点日志失败: %v", err)
+	}
+	entries, err := zentao.LoadJournal(path)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	targets := zentao.RollbackTargets(entries)
+	if err := confirm.checkCount(len(targets)); err != nil {
+		log.Fatal("%v", err)
+	}
+
+	separator := strings.Repeat("=", 60)
+	fmt.Printf("\n%s\n", separator)
+	fmt.Printf("           回滚导入 — 运行 %s\n", run)
+	fmt.Printf("%s\n\n", separator)
+	fmt.Printf("  检查点日志: %s\n\n", path)
+
+	if len(targets) == 0 {
+		fmt.Printf("该运行没有需要回滚的需求（未创建任何需求或已全部回滚）。\n")
+		log.Info("运行 %s 没有需要回滚的需求", run)
+		return
+	}
+
+	items := make([]zentao.TypedID, len(targets))
+	for idx, e := range targets {
+		items[idx] = zentao.TypedID{ID: e.StoryID, Type: story.StoryType(e.StoryType), Title: e.Title}
+	}
+	fmt.Print(zentao.FormatMatchedList(items))
+
+	// 二次确认
+	fmt.Printf("\n%s\n", separator)
+	fmt.Printf("\n⚠️  警告: 即将按以上顺序删除运行 %s 创建的 %d 个需求！\n", run, len(targets))
+	fmt.Printf("   此操作不可撤销！\n")
+	if !confirm.ask(log, "\n请输入 \"yes\" 确认回滚: ") {
+		log.Info("取消回滚操作")
+		return
+	}
+
+	client, err := zentao.NewClient(cfg)
+	if err != nil {
+		log.Fatal("创建禅道客户端失败: %v", err)
+	}
+
+	journal, err := zentao.OpenJournal(path)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+	defer journal.Close()
+
+	// 已回滚的需求从外部ID映射中移除，之后的幂等导入会重新创建
+	upsertMap, err := zentao.LoadUpsertMap(upsertMapPath)
+	if err != nil {
+		log.Fatal("%v", err)
+	}
+
+	deleter := zentao.NewDeleter(client, log)
+	deleter.SetUpsertMap(upsertMap)
+	results := deleter.Rollback(journal, targets)
+
+	// 生成并打印报告
+	report := deleter.GenerateDeleteReport(results)
+	log.Info("\n%s", report)
+
+	log.Info("日志文件已保存至: %s", log.GetLogFilePath())
+
+	hasFailure := false
+	for _, result := range results {
+		if !result.Success {
+			hasFailure = true
+		}
+	}
+	if hasFailure {
+		log.Error("部分需求未能删除，可修正问题后再次执行 -action rollback -run %s 重试", run)
+		os.Exit(1)
+	}
+}
+
+// handleDiff 处理比对操作
+// 比对Excel与禅道产品当前的需求，列出新增、删除和字段级修改，不修改任何数据
+func handleDiff(cfg *config.Config, log *logger.Logger, productID int, outputPath, errorReport string) {
+	stories := readStories(cfg, log, errorReport)
+
+	client, err := zentao.NewClient(cfg)
+	if err != nil {
+		log.Fatal("创建禅道客户端失败: %v", err)
+	}
+	resolveProducts(client, log, stories)
+
+	// 未指定产品时比对Excel涉及的全部产品
+	var productIDs []int
+	if productID > 0 {
+		productIDs = []int{productID}
+	} else {
+		seen := make(map[int]bool)
+		for _, s := range stories {
+			if !seen[s.ProductID] {
+				seen[s.ProductID] = true
+				productIDs = append(productIDs, s.ProductID)
+			}
+		}
+	}
+
+	// 模块路径解析为模块ID后再比对，无法解析的路径仅提示
+	for _, issue := range zentao.NewModuleResolver(client, log).Resolve(stories, cfg.GetDefaultModulePath(), false) {
+		log.Error("%s", issue)
+	}
+	// "#title:标题" 父需求解析为禅道ID后按父需求匹配，无法解析的仅提示
+	for _, issue := range zentao.NewParentTitleResolver(client, log).Resolve(stories) {
+		log.Error("%s", issue)
+	}
+
+	differ := zentao.NewDiffer(client, log)
+	var entries []zentao.DiffEntry
+	unchanged := 0
+	for _, id := range productIDs {
+		log.Info("正在比对产品 %d ...", id)
+		result, err := differ.Diff(id, stories)
+		if err != nil {
+			log.Fatal("比对失败: %v", err)
+		}
+		entries = append(entries, result.Entries...)
+		unchanged += result.Unchanged
+	}
+
+	separator := strings.Repeat("=", 60)
+	fmt.Printf("\n%s\n", separator)
+	fmt.Printf("           比对结果（Excel ↔ 禅道）\n")
+	fmt.Printf("%s\n\n", separator)
+	fmt.Print(zentao.FormatDiff(entries, unchanged))
+
+	if outputPath != "" {
+		if err := writeDiffReport(outputPath, entries); err != nil {
+			log.Fatal("导出差异报表失败: %v", err)
+		}
+		log.Info("差异报表已保存至: %s", outputPath)
+	}
+}
+
+// writeDiffReport 将比对结果导出为Excel报表（字段级修改每个字段一行）
+func writeDiffReport(outputPath string, entries []zentao.DiffEntry) error {
+	kindNames := map[string]string{
+		zentao.DiffAdded:    "新增",
+		zentao.DiffRemoved:  "删除",
+		zentao.DiffModified: "修改",
+	}
+	headers := []string{"差异类型", "产品ID", "需求类型", "标题", "Excel行号", "禅道ID", "字段", "禅道值", "Excel值"}
+	var rows [][]string
+	for _, e := range entries {
+		base := []string{kindNames[e.Kind], strconv.Itoa(e.ProductID), string(e.Type), e.Title, "", ""}
+		if e.RowIndex > 0 {
+			base[4] = strconv.Itoa(e.RowIndex)
+		}
+		if e.ZentaoID > 0 {
+			base[5] = strconv.Itoa(e.ZentaoID)
+		}
+		if len(e.Changes) == 0 {
+			rows = append(rows, append(base, "", "", ""))
+			continue
+		}
+		for _, c := range e.Changes {
+			row := append(append([]string{}, base...), c.Field, c.Zentao, c.Excel)
+			rows = append(rows, row)
+		}
+	}
+	return excel.WriteTable(outputPath, headers, rows)
+}
+
+// readStories 读取数据文件中的需求数据（Excel、CSV、JSON、YAML或Markdown，应用配置中的自定义列标题）
+// 校验失败时列出全部问题后退出；errorReport 非空时同时导出标注了错误单元格的副本（仅Excel）
+func readStories(cfg *config.Config, log *logger.Logger, errorReport string) []story.Story {
+	reader, err := excel.OpenStoryReader(cfg.ExcelFile, cfg.Format, sheetNames(cfg.Sheets))
+	if err != nil {
+		log.Fatal("创建数据读取器失败: %v", err)
+	}
+	defer reader.Close()
+
+	if err := reader.SetColumnAliases(cfg.Columns); err != nil {
+		log.Fatal("列映射配置错误: %v", err)
+	}
+
+	stories, err := reader.ReadStories(cfg.DefaultPriority)
+	var validationErrs excel.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		log.Error("Excel数据校验失败，共 %d 个问题:", len(validationErrs))
+		for _, e := range validationErrs {
+			log.Error("  %s", e.Error())
+		}
+		if errorReport != "" && cfg.Format != excel.FormatExcel {
+			log.Error("错误标注文件仅支持Excel数据文件，已忽略 -error-report")
+		} else if errorReport != "" {
+			if err := excel.ExportValidationReport(cfg.ExcelFile, errorReport, validationErrs); err != nil {
+				log.Error("导出错误标注文件失败: %v", err)
+			} else {
+				log.Info("已将错误标注到副本: %s", errorReport)
+			}
+		}
+		log.Fatal("请修正以上 %d 个问题后重试", len(validationErrs))
+	}
+	if err != nil {
+		log.Fatal("读取Excel数据失败: %v", err)
+	}
+	logSheetRanges(log, stories)
+	return stories
+}
+
+// applySheets 应用命令行 -sheets 参数（非空时覆盖配置文件），需在确定数据格式后调用
+// 数据文件不是Excel时清除来自配置文件的工作表并返回true；命令行显式指定的工作表保留，由 OpenStoryReader 报错
+func applySheets(cfg *config.Config, flagValue string) (ignored bool) {
+	if flagValue != "" {
+		cfg.Sheets = strings.Split(flagValue, ",")
+		return false
+	}
+	if cfg.Format != excel.FormatExcel && len(sheetNames(cfg.Sheets)) > 0 {
+		cfg.Sheets = nil
+		return true
+	}
+	return false
+}
+
+// sheetNames 规范化工作表配置：去除空白和空项，all 表示读取全部工作表
+func sheetNames(values []string) []string {
+	var names []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		if strings.EqualFold(v, "all") || v == excel.AllSheets {
+			return []string{excel.AllSheets}
+		}
+		names = append(names, v)
+	}
+	return names
+}
+
+// logSheetRanges 读取了多个工作表时，打印各工作表对应的行号范围（"@行号" 引用和导入报告中的行号为合并后的序号）
+func logSheetRanges(log *logger.Logger, stories []story.Story) {
+	type sheetRange struct {
+		name        string
+		first, last int
+	}
+	var ranges []sheetRange
+	for _, s := range stories {
+		if n := len(ranges); n > 0 && ranges[n-1].name == s.Sheet {
+			ranges[n-1].last = s.RowIndex
+			continue
+		}
+		ranges = append(ranges, sheetRange{name: s.Sheet, first: s.RowIndex, last: s.RowIndex})
+	}
+	if len(ranges) < 2 {
+		return
+	}
+	for _, r := range ranges {
+		log.Info("工作表 %s: 行%d-行%d", r.name, r.first, r.last)
+	}
+}
+
+// resolveProducts 将产品列填写的产品名称或代号解析为产品ID，无法解析或名称重复时退出
+// 所有行都填写产品ID时不查询产品列表
+func resolveProducts(client *zentao.Client, log *logger.Logger, stories []story.Story) {
+	hasRef := slices.ContainsFunc(stories, func(s story.Story) bool { return s.ProductRef != "" })
+	if !hasRef {
+		return
+	}
+	index, err := client.Product.Index()
+	if err != nil {
+		log.Fatal("获取产品列表失败，无法解析产品名称或代号: %v", err)
+	}
+	if issues := index.ResolveStories(stories); len(issues) > 0 {
+		for _, issue := range issues {
+			log.Error("%s", issue)
+		}
+		log.Fatal("发现 %d 个产品名称或代号问题，请修正后重试（可改为填写产品ID）", len(issues))
+	}
+}
+
 // loadConfig 从YAML文件加载配置，支持环境变量覆盖敏感字段
 func loadConfig(configFile string) (*config.Config, error) {
 	// 首先创建默认配置
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/internal/config"
+	"github.com/jan2xue/zentao_import_story/internal/excel"
+)
+
+func TestParseProductIDs(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"78", []int{78}, false},
+		{" 78, 79 ,78,", []int{78, 79}, false},
+		{"78,abc", nil, true},
+		{"0", nil, true},
+		{"-1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseProductIDs(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseProductIDs(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseProductIDs(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestConfirmOptions_CheckProducts(t *testing.T) {
+	if err := (confirmOptions{}).checkProducts([]int{1, 2}); err != nil {
+		t.Errorf("未指定 -confirm-product 时不应校验, 得到 %v", err)
+	}
+
+	confirm := confirmOptions{products: []int{78, 79}}
+	if err := confirm.checkProducts([]int{78}); err != nil {
+		t.Errorf("已确认的产品不应报错, 得到 %v", err)
+	}
+	err := confirm.checkProducts([]int{78, 100, 12})
+	if err == nil {
+		t.Fatal("涉及未确认的产品时期望返回错误")
+	}
+	if !strings.Contains(err.Error(), "12, 100") {
+		t.Errorf("错误信息应列出未确认的产品 12, 100, 得到 %v", err)
+	}
+}
+
+func TestConfirmOptions_CheckCount(t *testing.T) {
+	if err := (confirmOptions{}).checkCount(5); err != nil {
+		t.Errorf("未指定 -confirm-count 时不应校验, 得到 %v", err)
+	}
+
+	confirm := confirmOptions{count: 5}
+	if err := confirm.checkCount(5); err != nil {
+		t.Errorf("数量一致时不应报错, 得到 %v", err)
+	}
+	for _, count := range []int{0, 4, 6} {
+		if err := confirm.checkCount(count); err == nil {
+			t.Errorf("checkCount(%d) 期望返回错误（-confirm-count 5）", count)
+		}
+	}
+}
+
+func TestApplySheets(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		configured  []string
+		flagValue   string
+		wantSheets  []string
+		wantIgnored bool
+	}{
+		{"Excel使用配置", excel.FormatExcel, []string{"all"}, "", []string{"all"}, false},
+		{"命令行覆盖配置", excel.FormatExcel, []string{"all"}, "支付,会员", []string{"支付", "会员"}, false},
+		{"CSV忽略配置", excel.FormatCSV, []string{"all"}, "", nil, true},
+		{"CSV未配置", excel.FormatCSV, nil, "", nil, false},
+		{"CSV显式指定保留", excel.FormatCSV, []string{"all"}, "支付", []string{"支付"}, false},
+	}
+	for _, tt := range tests {
+		cfg := &config.Config{Format: tt.format, Sheets: tt.configured}
+		ignored := applySheets(cfg, tt.flagValue)
+		if ignored != tt.wantIgnored || !slices.Equal(cfg.Sheets, tt.wantSheets) {
+			t.Errorf("%s: applySheets() = %v, sheets %v, want %v, %v", tt.name, ignored, cfg.Sheets, tt.wantIgnored, tt.wantSheets)
+		}
+	}
+
+	// 命令行显式指定的工作表仍由 OpenStoryReader 拒绝
+	if _, err := excel.OpenStoryReader("stories.csv", excel.FormatCSV, []string{"支付"}); err == nil {
+		t.Error("非Excel文件显式指定工作表时期望返回错误")
+	}
+}
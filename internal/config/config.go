@@ -1,6 +1,13 @@
 // Package config 处理应用程序配置
 package config
 
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Config 存储程序配置信息
 type Config struct {
 	// 禅道系统配置
@@ -10,11 +17,45 @@ type Config struct {
 
 	// Excel文件配置
 	ExcelFile string `yaml:"excelFile"`
+	// 要读取的工作表名称，为空时只读取第一个工作表，填写 all 读取全部工作表
+	Sheets []string `yaml:"sheets"`
+	// 数据文件格式：excel、csv、json、yaml、markdown，为空时按文件扩展名判断
+	Format string `yaml:"format"`
 
 	// 默认值配置
-	DefaultPriority int    `yaml:"defaultPriority"` // 默认优先级 1-4
-	DefaultReviewer string `yaml:"defaultReviewer"` // 默认评审人（用户名）
-	DefaultModule   int    `yaml:"defaultModule"`   // 默认模块ID（用户需求需要）
+	DefaultPriority int           `yaml:"defaultPriority"` // 默认优先级 1-4
+	DefaultReviewer string        `yaml:"defaultReviewer"` // 默认评审人（账号或姓名）
+	DefaultModule   ModuleSetting `yaml:"defaultModule"`   // 默认模块：模块ID或模块路径（如 "支付/退款"）
+
+	DefaultAssignedTo string `yaml:"defaultAssignedTo"` // 默认指派给（账号或姓名），Excel"指派给"列为空时使用
+
+	// 父需求导入失败时子需求的处理策略：skip(跳过后代)、orphan(不设父需求继续创建，默认)、abort(中止导入)
+	OnParentFailure string `yaml:"onParentFailure"`
+
+	// 同类型子需求的最大层级（禅道需求的grade，如Story下的子Story为第2级），0表示不限制
+	MaxGrade int `yaml:"maxGrade"`
+	// 按产品ID单独配置的最大层级，覆盖 maxGrade
+	ProductMaxGrade map[int]int `yaml:"productMaxGrade"`
+
+	// 自定义列标题（字段名 -> Excel标题），如 title: "需求名称"，未配置的字段按内置中英文别名匹配
+	Columns map[string]string `yaml:"columns"`
+}
+
+// ModuleSetting 模块配置，可以填写模块ID（数字）或模块路径（如 "支付/退款/对账"）
+type ModuleSetting struct {
+	ID   int    // 模块ID，填写路径时为-1
+	Path string // 模块路径，填写数字时为空
+}
+
+// UnmarshalYAML 解析模块ID或模块路径
+func (m *ModuleSetting) UnmarshalYAML(node *yaml.Node) error {
+	value := strings.TrimSpace(node.Value)
+	if id, err := strconv.Atoi(value); err == nil {
+		*m = ModuleSetting{ID: id}
+		return nil
+	}
+	*m = ModuleSetting{ID: -1, Path: value}
+	return nil
 }
 
 // NewDefaultConfig 返回默认配置
@@ -24,8 +65,25 @@ func NewDefaultConfig() *Config {
 	}
 }
 
-// GetDefaultModule 实现 zentao.ConfigProvider 接口
-func (c *Config) GetDefaultModule() int { return c.DefaultModule }
+// GetDefaultModule 实现 zentao.ConfigProvider 接口（默认模块为路径时返回-1，由模块路径解析器写入需求）
+func (c *Config) GetDefaultModule() int { return c.DefaultModule.ID }
+
+// GetDefaultModulePath 返回配置的默认模块路径（默认模块填写为ID时为空）
+func (c *Config) GetDefaultModulePath() string { return c.DefaultModule.Path }
 
 // GetDefaultReviewer 实现 zentao.ConfigProvider 接口
 func (c *Config) GetDefaultReviewer() string { return c.DefaultReviewer }
+
+// GetDefaultAssignedTo 实现 zentao.ConfigProvider 接口
+func (c *Config) GetDefaultAssignedTo() string { return c.DefaultAssignedTo }
+
+// GetUsername 实现 zentao.ConfigProvider 接口
+func (c *Config) GetUsername() string { return c.ZentaoUsername }
+
+// GetMaxGrade 实现 zentao.ConfigProvider 接口：返回产品允许的最大需求层级，0表示不限制
+func (c *Config) GetMaxGrade(productID int) int {
+	if max, ok := c.ProductMaxGrade[productID]; ok {
+		return max
+	}
+	return c.MaxGrade
+}
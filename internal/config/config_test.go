@@ -2,6 +2,8 @@ package config
 
 import (
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestNewDefaultConfig(t *testing.T) {
@@ -28,3 +30,35 @@ func TestConfig_Values(t *testing.T) {
 		t.Errorf("优先级不匹配")
 	}
 }
+
+func TestConfig_GetMaxGrade(t *testing.T) {
+	cfg := &Config{MaxGrade: 3, ProductMaxGrade: map[int]int{78: 2, 79: 0}}
+
+	if got := cfg.GetMaxGrade(1); got != 3 {
+		t.Errorf("未单独配置的产品应使用 maxGrade 3, 得到 %d", got)
+	}
+	if got := cfg.GetMaxGrade(78); got != 2 {
+		t.Errorf("产品78应使用单独配置的 2, 得到 %d", got)
+	}
+	if got := cfg.GetMaxGrade(79); got != 0 {
+		t.Errorf("产品79单独配置为不限制(0), 得到 %d", got)
+	}
+}
+
+func TestConfig_DefaultModule(t *testing.T) {
+	var byID Config
+	if err := yaml.Unmarshal([]byte("defaultModule: 5\n"), &byID); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if byID.GetDefaultModule() != 5 || byID.GetDefaultModulePath() != "" {
+		t.Errorf("模块ID配置解析为 %+v, want ID=5", byID.DefaultModule)
+	}
+
+	var byPath Config
+	if err := yaml.Unmarshal([]byte("defaultModule: 支付/退款\n"), &byPath); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if byPath.GetDefaultModule() != -1 || byPath.GetDefaultModulePath() != "支付/退款" {
+		t.Errorf("模块路径配置解析为 %+v, want ID=-1 Path=支付/退款", byPath.DefaultModule)
+	}
+}
@@ -0,0 +1,166 @@
+// Package excel 处理Excel文件的读写操作 - 按标题行定位列
+package excel
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 列字段名（config.yaml 中 columns 映射的键）
+const (
+	ColumnType       = "type"
+	ColumnProduct    = "product"
+	ColumnModule     = "module"
+	ColumnTitle      = "title"
+	ColumnPriority   = "pri"
+	ColumnCategory   = "category"
+	ColumnSpec       = "spec"
+	ColumnParent     = "parent"
+	ColumnSource     = "source"
+	ColumnSourceNote = "sourceNote"
+	ColumnEstimate   = "estimate"
+	ColumnKeywords   = "keywords"
+	ColumnVerify     = "verify"
+	ColumnExternalID = "externalID"
+	ColumnZentaoID   = "zentaoID"
+	ColumnAssignedTo = "assignedTo"
+	ColumnReviewer   = "reviewer"
+	ColumnProject    = "project"
+	ColumnExecution  = "execution"
+	ColumnPlan       = "plan"
+	ColumnRefKey     = "refKey"
+)
+
+// columnDef 列定义：字段名、可识别的标题别名（第一个为模板标题）、是否必填
+type columnDef struct {
+	field    string
+	aliases  []string
+	required bool
+}
+
+// columnDefs 全部列定义，前13项的顺序与 TemplateHeaders 一致（旧版固定列位置）
+var columnDefs = []columnDef{
+	{ColumnType, []string{"需求类型", "类型", "Type", "Story Type"}, true},
+	{ColumnProduct, []string{"产品ID", "产品", "Product", "Product ID", "ProductID"}, true},
+	{ColumnModule, []string{"模块ID", "模块", "模块路径", "Module", "Module ID", "ModuleID"}, false},
+	{ColumnTitle, []string{"标题", "需求名称", "Title", "Name"}, true},
+	{ColumnPriority, []string{"优先级", "Priority", "Pri"}, false},
+	{ColumnCategory, []string{"分类", "类别", "Category"}, true},
+	{ColumnSpec, []string{"需求描述", "描述", "Spec", "Description"}, true},
+	{ColumnParent, []string{"父需求ID", "父需求", "Parent", "Parent ID", "ParentID"}, false},
+	{ColumnSource, []string{"来源", "Source"}, false},
+	{ColumnSourceNote, []string{"来源备注", "Source Note", "SourceNote"}, false},
+	{ColumnEstimate, []string{"预计工时", "工时", "Estimate"}, false},
+	{ColumnKeywords, []string{"关键词", "Keywords"}, false},
+	{ColumnVerify, []string{"验收标准", "Verify", "Acceptance Criteria"}, false},
+	{ColumnExternalID, []string{HeaderExternalID, "External ID", "ExternalID"}, false},
+	{ColumnZentaoID, []string{HeaderZentaoID, "ZenTao ID", "ZentaoID"}, false},
+	{ColumnAssignedTo, []string{"指派给", "指派人", "Assigned To", "AssignedTo", "Assignee"}, false},
+	{ColumnReviewer, []string{"评审人", "Reviewer", "Reviewers"}, false},
+	{ColumnProject, []string{"项目", "所属项目", "Project"}, false},
+	{ColumnExecution, []string{"执行", "迭代", "所属执行", "Execution", "Sprint"}, false},
+	{ColumnPlan, []string{"计划", "产品计划", "所属计划", "Plan"}, false},
+	{ColumnRefKey, []string{"引用键", "Ref Key", "RefKey"}, false},
+}
+
+// legacyColumns 旧版固定列位置（未读取标题行时使用，如直接调用parseRow）
+var legacyColumns = func() map[string]int {
+	columns := make(map[string]int, len(TemplateHeaders))
+	for i := range TemplateHeaders {
+		columns[columnDefs[i].field] = i
+	}
+	return columns
+}()
+
+// SetColumnAliases 设置自定义列标题（字段名 -> Excel标题），优先于内置别名匹配
+// 字段名见 Column* 常量，未知字段名返回错误
+func (r *Reader) SetColumnAliases(aliases map[string]string) error {
+	var unknown []string
+	for field := range aliases {
+		if findColumnDef(field) == nil {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("columns 配置中存在未知的字段: %s，支持: %s", strings.Join(unknown, ", "), strings.Join(columnFields(), ", "))
+	}
+	r.aliases = aliases
+	return nil
+}
+
+// locateColumns 根据标题行定位各字段所在的列（0-based），缺少必填列时返回错误
+// 同一字段按别名优先级匹配：自定义标题 > 模板标题 > 其他别名；列顺序和多余的列不影响读取
+// 工作表默认值区块提供了默认值的字段（如默认产品）不再要求必须有该列
+func locateColumns(header []string, custom map[string]string, defaults map[string]string) (map[string]int, error) {
+	columns := make(map[string]int)
+	var missing []string
+	for _, def := range columnDefs {
+		if col, ok := findHeader(header, fieldAliases(def.field, custom)); ok {
+			columns[def.field] = col
+		} else if def.required && defaults[def.field] == "" {
+			missing = append(missing, columnDisplayName(def, custom))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Excel标题行缺少必填列: %s（可在config.yaml的columns中配置自定义列标题）", strings.Join(missing, "、"))
+	}
+	return columns, nil
+}
+
+// findHeader 按别名顺序在标题行中查找列（忽略首尾空白和英文大小写）
+func findHeader(header []string, aliases []string) (int, bool) {
+	for _, alias := range aliases {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), alias) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// IsHeader 判断标题是否为指定字段的列（用于回写时匹配已有列）
+func IsHeader(field, header string) bool {
+	def := findColumnDef(field)
+	if def == nil {
+		return false
+	}
+	_, ok := findHeader([]string{header}, def.aliases)
+	return ok
+}
+
+// findColumnDef 按字段名查找列定义
+func findColumnDef(field string) *columnDef {
+	for i := range columnDefs {
+		if columnDefs[i].field == field {
+			return &columnDefs[i]
+		}
+	}
+	return nil
+}
+
+// columnFields 返回全部字段名
+func columnFields() []string {
+	fields := make([]string, len(columnDefs))
+	for i, def := range columnDefs {
+		fields[i] = def.field
+	}
+	return fields
+}
+
+// columnDisplayName 返回列的显示名称，如 "标题(Title)"，配置了自定义标题时使用自定义标题
+func columnDisplayName(def columnDef, custom map[string]string) string {
+	if name := strings.TrimSpace(custom[def.field]); name != "" {
+		return fmt.Sprintf("%s(%s)", name, def.field)
+	}
+	english := def.field
+	for _, alias := range def.aliases {
+		if alias[0] < 0x80 {
+			english = alias
+			break
+		}
+	}
+	return fmt.Sprintf("%s(%s)", def.aliases[0], english)
+}
@@ -1,7 +1,15 @@
 package excel
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
 )
 
 func TestReader_parseRow(t *testing.T) {
@@ -39,10 +47,16 @@ func TestReader_parseRow(t *testing.T) {
 		},
 		{
 			name:            "无效的产品ID",
-			row:             []string{"story", "invalid", "", "标题", "2", "feature", "描述"},
+			row:             []string{"story", "0", "", "标题", "2", "feature", "描述"},
 			defaultPriority: 3,
 			wantErr:         true,
 		},
+		{
+			name:            "产品名称或代号",
+			row:             []string{"story", "支付中心", "", "标题", "2", "feature", "描述"},
+			defaultPriority: 3,
+			wantErr:         false,
+		},
 		{
 			name:            "无效的需求类型",
 			row:             []string{"invalid_type", "1", "", "标题", "2", "feature", "描述"},
@@ -62,8 +76,14 @@ func TestReader_parseRow(t *testing.T) {
 			wantErr:         false,
 		},
 		{
-			name:            "模块ID非数字",
-			row:             []string{"story", "1", "abc", "标题", "2", "feature", "描述"},
+			name:            "模块路径",
+			row:             []string{"story", "1", "支付/退款/对账", "标题", "2", "feature", "描述"},
+			defaultPriority: 3,
+			wantErr:         false,
+		},
+		{
+			name:            "模块路径只有分隔符",
+			row:             []string{"story", "1", " / ", "标题", "2", "feature", "描述"},
 			defaultPriority: 3,
 			wantErr:         true,
 		},
@@ -79,6 +99,18 @@ func TestReader_parseRow(t *testing.T) {
 			defaultPriority: 3,
 			wantErr:         false,
 		},
+		{
+			name:            "父需求引用格式错误",
+			row:             []string{"story", "1", "", "子需求", "2", "feature", "描述", "@0"},
+			defaultPriority: 3,
+			wantErr:         true,
+		},
+		{
+			name:            "预计工时非数字",
+			row:             []string{"story", "1", "", "标题", "2", "feature", "描述", "", "", "", "abc"},
+			defaultPriority: 3,
+			wantErr:         true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -90,3 +122,834 @@ func TestReader_parseRow(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter_WriteResults(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.xlsx")
+
+	f := excelize.NewFile()
+	sheet := f.GetSheetName(0)
+	_ = f.SetSheetRow(sheet, "A1", &[]interface{}{"需求类型", "产品ID", "模块ID", "标题", "优先级", "分类", "需求描述", "禅道ID"})
+	_ = f.SetSheetRow(sheet, "A2", &[]interface{}{"epic", 1, "", "E", 1, "feature", "d"})
+	_ = f.SetSheetRow(sheet, "A3", &[]interface{}{"story", 1, "", "S", 1, "feature", "d"})
+	if err := f.SaveAs(src); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+
+	w, err := NewWriter(src)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	err = w.WriteResults([]RowResult{
+		{RowIndex: 1, ID: 501, Status: "成功"},
+		{RowIndex: 2, Status: "失败", Message: "HTTP 500"},
+	})
+	if err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+	out := filepath.Join(dir, "out.xlsx")
+	if err := w.Save(out); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	w.Close()
+
+	got, err := excelize.OpenFile(out)
+	if err != nil {
+		t.Fatalf("打开输出文件失败: %v", err)
+	}
+	defer got.Close()
+
+	// 已存在的"禅道ID"列(H)直接填充，状态和错误信息追加到I、J列
+	checks := map[string]string{
+		"H2": "501", "I1": HeaderImportStatus, "I2": "成功",
+		"J1": HeaderErrorMessage, "H3": "", "I3": "失败", "J3": "HTTP 500",
+	}
+	for cell, want := range checks {
+		if v, _ := got.GetCellValue(sheet, cell); v != want {
+			t.Errorf("单元格%s = %q, want %q", cell, v, want)
+		}
+	}
+}
+
+func TestExportStories_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.xlsx")
+	exported := []story.Story{
+		{Type: story.StoryTypeEpic, ProductID: 78, Module: 0, Title: "E", Priority: 1, Category: "feature", Spec: "d", RowIndex: 1},
+		{Type: story.StoryTypeStory, ProductID: 78, Module: 5, Title: "S", Priority: 3, Category: "feature", Spec: "d", ParentRef: "@1", Estimate: 2.5, RowIndex: 2},
+	}
+	if err := ExportStories(path, exported); err != nil {
+		t.Fatalf("ExportStories() error = %v", err)
+	}
+
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	if len(stories) != 2 {
+		t.Fatalf("期望2条, 得到 %d", len(stories))
+	}
+	got := stories[1]
+	if got.Type != story.StoryTypeStory || got.ParentRef != "@1" || got.Module != 5 || got.Estimate != 2.5 || got.ProductID != 78 {
+		t.Errorf("重新读取的数据不一致: %+v", got)
+	}
+}
+
+func TestExportStories_ModuleColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.xlsx")
+	exported := []story.Story{
+		{Type: story.StoryTypeEpic, ProductID: 78, Module: 5, ModulePath: "/前端/支付", Title: "E", Category: "feature", Spec: "d", RowIndex: 1},
+		{Type: story.StoryTypeEpic, ProductID: 78, Module: 0, Title: "E2", Category: "feature", Spec: "d", RowIndex: 2},
+		{Type: story.StoryTypeEpic, ProductID: 78, Module: -1, Title: "E3", Category: "feature", Spec: "d", RowIndex: 3},
+		{Type: story.StoryTypeEpic, ProductID: 78, Module: 7, ModulePath: "/2024", Title: "E4", Category: "feature", Spec: "d", RowIndex: 4},
+	}
+	if err := ExportStories(path, exported); err != nil {
+		t.Fatalf("ExportStories() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	rows, err := f.GetRows(f.GetSheetName(0))
+	f.Close()
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	wantCells := []string{"/前端/支付", "0", "", "/2024"}
+	for idx, want := range wantCells {
+		if got := rows[idx+1][2]; got != want {
+			t.Errorf("第%d行模块列 = %q, want %q", idx+1, got, want)
+		}
+	}
+
+	// 重新读取时模块列按路径解析，纯数字的模块名也不会被当作模块ID
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	wantRead := []struct {
+		module int
+		path   string
+	}{{-1, "前端/支付"}, {0, ""}, {-1, ""}, {-1, "2024"}}
+	for idx, want := range wantRead {
+		if got := stories[idx]; got.Module != want.module || got.ModulePath != want.path {
+			t.Errorf("第%d行 模块ID=%d 路径=%q, want %d %q", idx+1, got.Module, got.ModulePath, want.module, want.path)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.xlsx")
+	headers := []string{"差异类型", "标题"}
+	rows := [][]string{{"新增", "A"}, {"删除", "B"}}
+	if err := WriteTable(path, headers, rows); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+	got, err := f.GetRows(f.GetSheetName(0))
+	if err != nil {
+		t.Fatalf("GetRows() error = %v", err)
+	}
+	if len(got) != 3 || got[0][0] != "差异类型" || got[2][1] != "B" {
+		t.Errorf("写入内容不一致: %v", got)
+	}
+}
+
+// writeSheet 创建只包含给定行的临时Excel文件
+func writeSheet(t *testing.T, rows [][]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "input.xlsx")
+	if err := WriteTable(path, rows[0], rows[1:]); err != nil {
+		t.Fatalf("WriteTable() error = %v", err)
+	}
+	return path
+}
+
+func TestReader_ReadStories_HeaderMapping(t *testing.T) {
+	// 列顺序打乱、使用英文标题并带有多余的列
+	path := writeSheet(t, [][]string{
+		{"备注", "Title", "Type", "Spec", "Product ID", "Category", "Priority", "Parent"},
+		{"忽略", "子需求", "story", "描述", "78", "feature", "2", "@1"},
+	})
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	got := stories[0]
+	if got.Title != "子需求" || got.Type != story.StoryTypeStory || got.ProductID != 78 || got.Priority != 2 ||
+		got.Spec != "描述" || got.ParentRef != "@1" || got.Module != -1 {
+		t.Errorf("按标题读取的数据不一致: %+v", got)
+	}
+}
+
+func TestReader_ReadStories_AssigneeAndReviewers(t *testing.T) {
+	header := []string{"需求类型", "产品ID", "标题", "分类", "需求描述", "指派给", "评审人"}
+	path := writeSheet(t, [][]string{
+		header,
+		{"story", "78", "多评审人", "feature", "描述", "zhangsan", "lisi, wangwu，lisi、zhaoliu"},
+		{"story", "78", "未填写", "feature", "描述", "", ""},
+	})
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	if got := stories[0]; got.AssignedTo != "zhangsan" || strings.Join(got.Reviewers, ",") != "lisi,wangwu,zhaoliu" {
+		t.Errorf("指派给/评审人 = %q / %q", got.AssignedTo, got.Reviewers)
+	}
+	if got := stories[1]; got.AssignedTo != "" || got.Reviewers != nil {
+		t.Errorf("未填写时应为空, 得到 %q / %q", got.AssignedTo, got.Reviewers)
+	}
+
+	// 指派给只能填写一个账号
+	invalid := writeSheet(t, [][]string{header, {"story", "78", "多个指派", "feature", "描述", "a,b", ""}})
+	reader2, err := NewReader(invalid)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader2.Close()
+
+	_, err = reader2.ReadStories(3)
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 1 || verrs[0].Header != "指派给" {
+		t.Fatalf("期望指派给列报错, 得到 %v", err)
+	}
+}
+
+func TestReader_ReadStories_CustomColumns(t *testing.T) {
+	path := writeSheet(t, [][]string{
+		{"需求类型", "产品ID", "需求标题", "分类", "需求描述"},
+		{"epic", "1", "业务需求", "feature", "描述"},
+	})
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	// 未配置自定义标题时报告缺少的必填列
+	_, err = reader.ReadStories(3)
+	if err == nil || !strings.Contains(err.Error(), "标题(Title)") {
+		t.Fatalf("期望缺少标题列的错误, 得到 %v", err)
+	}
+
+	if err := reader.SetColumnAliases(map[string]string{"unknown": "x"}); err == nil {
+		t.Error("未知字段名应返回错误")
+	}
+	if err := reader.SetColumnAliases(map[string]string{ColumnTitle: "需求标题"}); err != nil {
+		t.Fatalf("SetColumnAliases() error = %v", err)
+	}
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	if stories[0].Title != "业务需求" || stories[0].Type != story.StoryTypeEpic {
+		t.Errorf("自定义标题读取的数据不一致: %+v", stories[0])
+	}
+}
+
+func TestReader_ReadStories_CollectsAllErrors(t *testing.T) {
+	path := writeSheet(t, [][]string{
+		TemplateHeaders,
+		{"story", "1", "", "正常", "2", "feature", "描述"},
+		{"bad", "-3", "", "标题", "9", "feature", "描述"},
+		{"story", "1", "", "标题", "2", "", "描述", "@a b", "", "", "三小时"},
+	})
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("期望 ValidationErrors, 得到 %v", err)
+	}
+
+	want := []struct {
+		row    int
+		header string
+	}{
+		{3, "需求类型"}, {3, "产品ID"}, {3, "优先级"},
+		{4, "分类"}, {4, "父需求ID"}, {4, "预计工时"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("期望%d个问题, 得到 %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Row != w.row || errs[i].Header != w.header {
+			t.Errorf("第%d个问题 = 行%d [%s], want 行%d [%s]", i+1, errs[i].Row, errs[i].Header, w.row, w.header)
+		}
+	}
+
+	// 导出错误标注副本：错误单元格带批注，源文件不变
+	reportPath := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := ExportValidationReport(path, reportPath, errs); err != nil {
+		t.Fatalf("ExportValidationReport() error = %v", err)
+	}
+	f, err := excelize.OpenFile(reportPath)
+	if err != nil {
+		t.Fatalf("打开文件失败: %v", err)
+	}
+	defer f.Close()
+	comments, err := f.GetComments(f.GetSheetName(0))
+	if err != nil {
+		t.Fatalf("GetComments() error = %v", err)
+	}
+	if len(comments) != len(want) {
+		t.Errorf("期望%d条批注, 得到 %d", len(want), len(comments))
+	}
+}
+
+func TestReader_parseRow_ModulePath(t *testing.T) {
+	reader := &Reader{}
+
+	s, err := reader.parseRow([]string{"story", "1", " 支付 / 退款/对账/ ", "标题", "2", "feature", "描述"}, 3, 1)
+	if err != nil {
+		t.Fatalf("parseRow() error = %v", err)
+	}
+	if s.ModulePath != "支付/退款/对账" {
+		t.Errorf("ModulePath = %q, want %q", s.ModulePath, "支付/退款/对账")
+	}
+	if s.Module != -1 {
+		t.Errorf("填写模块路径时 Module = %d, want -1（导入前解析）", s.Module)
+	}
+}
+
+func TestReader_parseRow_ProductRef(t *testing.T) {
+	reader := &Reader{}
+
+	s, err := reader.parseRow([]string{"story", "PAY", "", "标题", "2", "feature", "描述"}, 3, 1)
+	if err != nil {
+		t.Fatalf("parseRow() error = %v", err)
+	}
+	if s.ProductRef != "PAY" || s.ProductID != 0 {
+		t.Errorf("ProductRef/ProductID = %q/%d, want PAY/0（导入前解析）", s.ProductRef, s.ProductID)
+	}
+}
+
+func TestReader_ReadStories_ProjectExecutionPlan(t *testing.T) {
+	header := []string{"需求类型", "产品ID", "标题", "分类", "需求描述", "项目", "迭代", "计划"}
+	path := writeSheet(t, [][]string{
+		header,
+		{"story", "78", "按ID", "feature", "描述", "10", "101", "7"},
+		{"story", "78", "按名称", "feature", "描述", "支付二期", "Sprint 1", "2026Q1"},
+		{"requirement", "78", "用户需求", "feature", "描述", "", "", "2026Q1"},
+	})
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	if got := stories[0]; got.Project != 10 || got.Execution != 101 || got.Plan != 7 || got.ProjectRef != "" {
+		t.Errorf("按ID填写 = %+v", got)
+	}
+	if got := stories[1]; got.ProjectRef != "支付二期" || got.ExecutionRef != "Sprint 1" || got.PlanRef != "2026Q1" || got.Project != 0 {
+		t.Errorf("按名称填写 = %+v", got)
+	}
+
+	// 只有研发需求可以关联项目/执行，业务需求不能关联计划
+	invalid := writeSheet(t, [][]string{
+		header,
+		{"requirement", "78", "用户需求", "feature", "描述", "10", "", ""},
+		{"epic", "78", "业务需求", "feature", "描述", "", "", "7"},
+		{"story", "78", "研发需求", "feature", "描述", "0", "", ""},
+	})
+	reader2, err := NewReader(invalid)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader2.Close()
+
+	_, err = reader2.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 3 {
+		t.Fatalf("期望3个问题, 得到 %v", err)
+	}
+	for n, header := range []string{"项目", "计划", "项目"} {
+		if errs[n].Header != header {
+			t.Errorf("第%d个问题列 = %s, want %s", n+1, errs[n].Header, header)
+		}
+	}
+}
+
+// writeWorkbook 创建包含多个工作表的测试文件，sheets 按 名称、行数据 交替给出
+func writeWorkbook(t *testing.T, sheets ...interface{}) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for n := 0; n < len(sheets); n += 2 {
+		name := sheets[n].(string)
+		if n == 0 {
+			_ = f.SetSheetName(f.GetSheetName(0), name)
+		} else if _, err := f.NewSheet(name); err != nil {
+			t.Fatalf("创建工作表失败: %v", err)
+		}
+		for i, row := range sheets[n+1].([][]string) {
+			values := make([]interface{}, len(row))
+			for j, v := range row {
+				values[j] = v
+			}
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			_ = f.SetSheetRow(name, cell, &values)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "workbook.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	return path
+}
+
+func TestReader_ReadStories_MultipleSheets(t *testing.T) {
+	header := []string{"需求类型", "标题", "分类", "需求描述", "父需求ID"}
+	path := writeWorkbook(t,
+		"说明", [][]string{{"本文件按产品分页"}},
+		"支付", [][]string{
+			{"默认产品", "78"},
+			{"默认模块", "支付/退款"},
+			{},
+			header,
+			{"epic", "E", "feature", "描述", ""},
+			{"requirement", "R", "feature", "描述", "@1"},
+		},
+		"会员", [][]string{
+			{"默认产品", "会员中心"},
+			header,
+			{"story", "S1", "feature", "描述", "@支付!2"},
+			{"story", "S2", "feature", "描述", "@1"},
+		},
+	)
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+	reader.SetSheets([]string{AllSheets})
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	want := []struct {
+		rowIndex   int
+		sheet      string
+		sheetRow   int
+		productID  int
+		productRef string
+		modulePath string
+		parentRef  string
+	}{
+		{1, "支付", 5, 78, "", "支付/退款", ""},
+		{2, "支付", 6, 78, "", "支付/退款", "@1"},
+		{3, "会员", 3, 0, "会员中心", "", "@2"},
+		{4, "会员", 4, 0, "会员中心", "", "@3"},
+	}
+	if len(stories) != len(want) {
+		t.Fatalf("读取到 %d 条需求, want %d（说明页应被跳过）", len(stories), len(want))
+	}
+	for n, w := range want {
+		s := stories[n]
+		if s.RowIndex != w.rowIndex || s.Sheet != w.sheet || s.SheetRow != w.sheetRow || s.ProductID != w.productID ||
+			s.ProductRef != w.productRef || s.ModulePath != w.modulePath || s.ParentRef != w.parentRef {
+			t.Errorf("第%d条 = 行%d %s!%d 产品%d/%q 模块%q 父%q, want %+v",
+				n+1, s.RowIndex, s.Sheet, s.SheetRow, s.ProductID, s.ProductRef, s.ModulePath, s.ParentRef, w)
+		}
+	}
+
+	// 只读取指定工作表时，引用未读取的工作表报错
+	reader.SetSheets([]string{"会员"})
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Sheet != "会员" || errs[0].Row != 3 || errs[0].Header != "父需求ID" {
+		t.Fatalf("期望会员!3 父需求ID列报错, 得到 %v", err)
+	}
+
+	reader.SetSheets([]string{"不存在"})
+	if _, err := reader.ReadStories(3); err == nil || !strings.Contains(err.Error(), "工作表不存在") {
+		t.Errorf("期望工作表不存在错误, 得到 %v", err)
+	}
+}
+
+func TestWriter_WriteResults_Sheets(t *testing.T) {
+	path := writeWorkbook(t,
+		"A", [][]string{{"需求类型", "标题"}, {"epic", "E"}},
+		"B", [][]string{{"默认产品", "78"}, {"需求类型", "标题"}, {"story", "S"}},
+	)
+	w, err := NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer w.Close()
+	if err := w.WriteResults([]RowResult{
+		{RowIndex: 1, Sheet: "A", SheetRow: 2, ID: 1, Status: "成功"},
+		{RowIndex: 2, Sheet: "B", SheetRow: 3, ID: 2, Status: "成功"},
+	}); err != nil {
+		t.Fatalf("WriteResults() error = %v", err)
+	}
+
+	checks := []struct{ sheet, cell, want string }{
+		{"A", "C1", HeaderZentaoID}, {"A", "C2", "1"},
+		{"B", "C2", HeaderZentaoID}, {"B", "C3", "2"}, {"B", "C1", ""},
+	}
+	for _, c := range checks {
+		if v, _ := w.file.GetCellValue(c.sheet, c.cell); v != c.want {
+			t.Errorf("%s!%s = %q, want %q", c.sheet, c.cell, v, c.want)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path, format, want string
+		wantErr            bool
+	}{
+		{path: "stories.xlsx", want: FormatExcel},
+		{path: "stories.CSV", want: FormatCSV},
+		{path: "stories.json", want: FormatJSON},
+		{path: "stories.yml", want: FormatYAML},
+		{path: "stories.txt", want: FormatExcel},
+		{path: "stories.txt", format: "csv", want: FormatCSV},
+		{path: "stories.xlsx", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.path, tt.format)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %q, %v, want %q", tt.path, tt.format, got, err, tt.want)
+		}
+	}
+}
+
+// writeFile 在临时目录中写入测试数据文件
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("创建测试文件失败: %v", err)
+	}
+	return path
+}
+
+func TestOpenStoryReader_CSV(t *testing.T) {
+	content := "需求类型,产品ID,标题,分类,需求描述,父需求ID,评审人\n" +
+		"epic,78,会员体系,feature,描述,,\n" +
+		"story,78,\"积分,兑换\",feature,描述,@1,\"zhangsan,lisi\"\n"
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(content)
+	if err != nil {
+		t.Fatalf("GBK编码失败: %v", err)
+	}
+
+	for name, data := range map[string][]byte{
+		"utf8.csv": []byte("\xEF\xBB\xBF" + content),
+		"gbk.csv":  []byte(gbk),
+	} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := OpenStoryReader(writeFile(t, name, data), "", nil)
+			if err != nil {
+				t.Fatalf("OpenStoryReader() error = %v", err)
+			}
+			defer reader.Close()
+			stories, err := reader.ReadStories(3)
+			if err != nil {
+				t.Fatalf("ReadStories() error = %v", err)
+			}
+			if len(stories) != 2 {
+				t.Fatalf("读取到 %d 个需求, want 2", len(stories))
+			}
+			s := stories[1]
+			if s.Title != "积分,兑换" || s.ParentRef != "@1" || s.RowIndex != 2 || strings.Join(s.Reviewers, ",") != "zhangsan,lisi" {
+				t.Errorf("第2行解析结果 = %+v", s)
+			}
+		})
+	}
+}
+
+func TestOpenStoryReader_Records(t *testing.T) {
+	jsonData := `{"stories": [
+		{"type": "epic", "product": 78, "title": "会员体系", "category": "feature", "spec": "描述"},
+		{"type": "story", "product": "PAY", "title": "积分兑换", "category": "feature", "spec": "描述",
+		 "parent": "@1", "estimate": 1.5, "reviewer": ["zhangsan", "lisi"]}
+	]}`
+	yamlData := `- 需求类型: epic
+  产品ID: 78
+  标题: 会员体系
+  分类: feature
+  需求描述: 描述
+- 需求类型: story
+  产品ID: PAY
+  标题: 积分兑换
+  分类: feature
+  需求描述: 描述
+  父需求ID: "@1"
+  预计工时: 1.5
+  评审人: [zhangsan, lisi]
+`
+	for name, data := range map[string]string{"stories.json": jsonData, "stories.yaml": yamlData} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := OpenStoryReader(writeFile(t, name, []byte(data)), "", nil)
+			if err != nil {
+				t.Fatalf("OpenStoryReader() error = %v", err)
+			}
+			stories, err := reader.ReadStories(3)
+			if err != nil {
+				t.Fatalf("ReadStories() error = %v", err)
+			}
+			if len(stories) != 2 || stories[0].ProductID != 78 {
+				t.Fatalf("读取结果 = %+v", stories)
+			}
+			s := stories[1]
+			if s.ProductRef != "PAY" || s.Title != "积分兑换" || s.ParentRef != "@1" || s.Estimate != 1.5 ||
+				strings.Join(s.Reviewers, ",") != "zhangsan,lisi" {
+				t.Errorf("第2条记录解析结果 = %+v", s)
+			}
+		})
+	}
+}
+
+func TestOpenStoryReader_RecordErrors(t *testing.T) {
+	data := `[{"type": "epic", "product": 78, "title": "会员体系", "category": "feature", "spec": "描述"},
+		{"type": "story", "product": 78, "title": "积分", "category": "feature", "spec": "描述", "pri": 9}]`
+	reader, err := OpenStoryReader(writeFile(t, "stories.json", []byte(data)), "", nil)
+	if err != nil {
+		t.Fatalf("OpenStoryReader() error = %v", err)
+	}
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("期望1个校验错误, 得到 %v", err)
+	}
+	if got := errs[0].Error(); !strings.HasPrefix(got, "第2条记录 字段[pri]: 优先级必须是1-4之间的数字") {
+		t.Errorf("错误信息 = %q", got)
+	}
+
+	if _, err := OpenStoryReader(writeFile(t, "stories.json", []byte(data)), "", []string{"Sheet2"}); err == nil {
+		t.Error("非Excel文件指定工作表时期望返回错误")
+	}
+	if _, err := OpenStoryReader(writeFile(t, "bad.yaml", []byte("title: 单个对象")), "", nil); err == nil {
+		t.Error("YAML不是需求列表时期望返回错误")
+	}
+}
+
+func TestOpenStoryReader_Markdown(t *testing.T) {
+	content := `---
+product: 78
+category: feature
+reviewer: [zhangsan, lisi]
+---
+会议记录，不导入
+
+# 会员体系
+会员体系建设
+
+## 积分
+积分获取与消耗
+
+- 积分兑换
+  用户可使用积分兑换商品
+
+  兑换后扣减积分
+  - [ ] 兑换记录
+- 积分过期
+### 积分规则
+1. 规则配置
+` + "```" + `
+# 不是标题
+` + "```" + `
+`
+	reader, err := OpenStoryReader(writeFile(t, "stories.md", []byte(content)), "", nil)
+	if err != nil {
+		t.Fatalf("OpenStoryReader() error = %v", err)
+	}
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+
+	want := []struct {
+		typ       story.StoryType
+		title     string
+		spec      string
+		parentRef string
+	}{
+		{story.StoryTypeEpic, "会员体系", "会员体系建设", ""},
+		{story.StoryTypeRequirement, "积分", "积分获取与消耗", "@1"},
+		{story.StoryTypeStory, "积分兑换", "用户可使用积分兑换商品\n\n兑换后扣减积分", "@2"},
+		{story.StoryTypeStory, "兑换记录", "兑换记录", "@3"},
+		{story.StoryTypeStory, "积分过期", "积分过期", "@2"},
+		{story.StoryTypeRequirement, "积分规则", "积分规则", "@2"},
+		{story.StoryTypeStory, "规则配置", "```\n# 不是标题\n```", "@6"},
+	}
+	if len(stories) != len(want) {
+		t.Fatalf("读取到 %d 个需求, want %d: %+v", len(stories), len(want), stories)
+	}
+	for i, w := range want {
+		s := stories[i]
+		if s.Type != w.typ || s.Title != w.title || s.Spec != w.spec || s.ParentRef != w.parentRef {
+			t.Errorf("需求%d = {%s %q %q %q}, want %+v", i+1, s.Type, s.Title, s.Spec, s.ParentRef, w)
+		}
+		if s.ProductID != 78 || s.Category != "feature" || strings.Join(s.Reviewers, ",") != "zhangsan,lisi" {
+			t.Errorf("需求%d 未使用 front matter 默认值: %+v", i+1, s)
+		}
+	}
+}
+
+func TestOpenStoryReader_MarkdownErrors(t *testing.T) {
+	content := "---\nproduct: 78\n---\n# 会员体系\n- 积分兑换\n"
+	reader, err := OpenStoryReader(writeFile(t, "stories.md", []byte(content)), "", nil)
+	if err != nil {
+		t.Fatalf("OpenStoryReader() error = %v", err)
+	}
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("期望2个校验错误（缺少分类）, 得到 %v", err)
+	}
+	if got := errs[1].Error(); !strings.HasPrefix(got, "第5行 字段[category]: 分类不能为空") {
+		t.Errorf("错误信息 = %q", got)
+	}
+
+	if _, err := OpenStoryReader(writeFile(t, "bad.md", []byte("---\nproduc: 78\n---\n# 标题\n")), "", nil); err == nil {
+		t.Error("front matter 中存在未知字段时期望返回错误")
+	}
+}
+
+func TestReader_ReadStories_RefKeys(t *testing.T) {
+	header := []string{"需求类型", "产品ID", "标题", "分类", "需求描述", "父需求ID", "引用键"}
+	path := writeWorkbook(t,
+		"业务", [][]string{header,
+			{"epic", "78", "会员体系", "feature", "描述", "", "PAY-EPIC-1"},
+		},
+		"研发", [][]string{header,
+			{"story", "78", "积分兑换", "feature", "描述", "@PAY-REQ", ""},
+			{"requirement", "78", "积分", "feature", "描述", "@PAY-EPIC-1", "PAY-REQ"},
+		},
+	)
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+	reader.SetSheets([]string{AllSheets})
+
+	stories, err := reader.ReadStories(3)
+	if err != nil {
+		t.Fatalf("ReadStories() error = %v", err)
+	}
+	if got := stories[1]; got.ParentRef != "@3" || got.ParentKey != "PAY-REQ" {
+		t.Errorf("研发第2行 父需求 = %q (ParentKey %q), want @3", got.ParentRef, got.ParentKey)
+	}
+	if got := stories[2]; got.ParentRef != "@1" || got.RefKey != "PAY-REQ" {
+		t.Errorf("研发第3行 父需求 = %q, 引用键 = %q", got.ParentRef, got.RefKey)
+	}
+
+	// 重复的引用键和不存在的引用键
+	path = writeSheet(t, [][]string{header,
+		{"epic", "78", "会员体系", "feature", "描述", "", "DUP"},
+		{"epic", "78", "支付", "feature", "描述", "", "DUP"},
+		{"story", "78", "积分", "feature", "描述", "@NONE", "1"},
+	})
+	reader, err = NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("期望 ValidationErrors, 得到 %v", err)
+	}
+	want := []string{"第4行 G列[引用键]: 引用键 1 不能为纯数字", "第2行 G列[引用键]: 引用键 DUP 重复", "第3行 G列[引用键]: 引用键 DUP 重复"}
+	if len(errs) != len(want) {
+		t.Fatalf("期望%d个问题, 得到 %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if got := errs[i].Error(); !strings.HasPrefix(got, w) {
+			t.Errorf("第%d个问题 = %q, want prefix %q", i+1, got, w)
+		}
+	}
+}
+
+func TestReader_parseRow_ParentTitle(t *testing.T) {
+	reader := &Reader{}
+
+	s, err := reader.parseRow([]string{"story", "78", "", "积分兑换", "2", "feature", "描述", "#title:会员体系"}, 3, 1)
+	if err != nil {
+		t.Fatalf("parseRow() error = %v", err)
+	}
+	if s.ParentTitle != "会员体系" || s.ParentRef != "#title:会员体系" || s.ParentID != 0 {
+		t.Errorf("ParentTitle/ParentRef/ParentID = %q/%q/%d, want 会员体系/#title:会员体系/0（导入前解析）", s.ParentTitle, s.ParentRef, s.ParentID)
+	}
+
+	if _, err := reader.parseRow([]string{"story", "78", "", "积分兑换", "2", "feature", "描述", "#title: "}, 3, 1); err == nil {
+		t.Error("#title: 缺少标题时期望返回错误")
+	}
+}
+
+func TestReader_ReadStories_DuplicateExternalIDs(t *testing.T) {
+	header := []string{"需求类型", "产品ID", "标题", "分类", "需求描述", "外部ID"}
+	path := writeWorkbook(t,
+		"业务", [][]string{header,
+			{"epic", "78", "会员体系", "feature", "描述", "K-1"},
+			{"epic", "78", "支付", "feature", "描述", "K-2"},
+		},
+		"研发", [][]string{header,
+			{"story", "78", "积分兑换", "feature", "描述", "K-1"},
+			{"story", "78", "积分", "feature", "描述", ""},
+			{"story", "78", "兑换记录", "feature", "描述", ""},
+		},
+	)
+	reader, err := NewReader(path)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	defer reader.Close()
+	reader.SetSheets([]string{AllSheets})
+
+	_, err = reader.ReadStories(3)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("期望 ValidationErrors, 得到 %v", err)
+	}
+	want := []string{"工作表 业务 第2行 F列[外部ID]: 外部ID K-1 重复", "工作表 研发 第2行 F列[外部ID]: 外部ID K-1 重复"}
+	if len(errs) != len(want) {
+		t.Fatalf("期望%d个问题（未填写的外部ID不算重复）, 得到 %d:\n%v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if got := errs[i].Error(); !strings.HasPrefix(got, w) {
+			t.Errorf("第%d个问题 = %q, want prefix %q", i+1, got, w)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package excel 处理Excel文件的读写操作 - Markdown 大纲读取
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	markdownHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownBullet  = regexp.MustCompile(`^([-*+]|\d+[.)])\s+(.*)$`)
+	markdownTask    = regexp.MustCompile(`^\[[ xX]\]\s+`)
+)
+
+// markdownItem 大纲中的一个需求：标题或列表项
+type markdownItem struct {
+	line   int      // 源文件行号
+	typ    string   // 需求类型
+	title  string   // 需求标题
+	spec   []string // 正文行
+	parent int      // 父需求的数据行号（从1开始），0表示没有父需求
+}
+
+// markdownOpen 大纲中尚未结束的标题或列表项，用于确定后续条目的父需求
+type markdownOpen struct {
+	level int // 标题级别（1-6）或列表项缩进
+	row   int // 数据行号（从1开始）
+}
+
+// NewMarkdownReader 创建Markdown大纲读取器
+// "#" 标题为业务需求(epic)，"##" 及更深的标题为用户需求(requirement，归属于上一级标题)，列表项为研发需求(story)，
+// 嵌套的列表项为子需求；标题或列表项下方的正文为需求描述（列表项的正文需缩进），没有正文时以标题作为描述
+// 文件开头的 front matter（--- 包围的YAML）为全部需求的默认值，如 product、module、category、pri、reviewer
+func NewMarkdownReader(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开Markdown文件失败: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	defaults, start, err := parseFrontMatter(lines)
+	if err != nil {
+		return nil, err
+	}
+	items := parseMarkdownOutline(lines, start)
+
+	// 产品和分类列始终存在：front matter 未提供默认值时按行报告，而不是报告缺少列
+	header := []string{ColumnType, ColumnTitle, ColumnSpec, ColumnParent}
+	extra := []string{ColumnProduct, ColumnCategory}
+	for field := range defaults {
+		switch {
+		case findColumnDef(field) == nil:
+			return nil, fmt.Errorf("Markdown front matter 中存在未知的字段: %s，支持: %s", field, strings.Join(columnFields(), ", "))
+		case slices.Contains(header, field):
+			return nil, fmt.Errorf("Markdown front matter 不能设置字段 %s，该字段由大纲结构决定", field)
+		case !slices.Contains(extra, field):
+			extra = append(extra, field)
+		}
+	}
+	sort.Strings(extra[2:])
+	header = append(header, extra...)
+
+	rows := [][]string{header}
+	source := &sourceRows{unit: "行"}
+	for _, item := range items {
+		spec := strings.TrimSpace(strings.Join(item.spec, "\n"))
+		if spec == "" {
+			spec = item.title
+		}
+		parent := ""
+		if item.parent > 0 {
+			parent = "@" + strconv.Itoa(item.parent)
+		}
+		row := []string{item.typ, item.title, spec, parent}
+		for _, field := range extra {
+			row = append(row, defaults[field])
+		}
+		rows = append(rows, row)
+		source.lines = append(source.lines, item.line)
+	}
+	return &Reader{file: &memoryWorkbook{name: sourceName(path), rows: rows}, source: source}, nil
+}
+
+// parseFrontMatter 解析文件开头 "---" 包围的YAML默认值（键为字段名），返回默认值和正文开始的行下标
+func parseFrontMatter(lines []string) (map[string]string, int, error) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, 0, nil
+	}
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			end = i
+			break
+		}
+	}
+	if end < 0 {
+		return nil, 0, fmt.Errorf("Markdown front matter 缺少结束行 ---")
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[1:end], "\n")), &values); err != nil {
+		return nil, 0, fmt.Errorf("解析Markdown front matter失败: %w", err)
+	}
+	defaults := make(map[string]string, len(values))
+	for key, value := range values {
+		text, err := recordValue(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Markdown front matter 字段[%s]: %w", key, err)
+		}
+		defaults[key] = text
+	}
+	return defaults, end + 1, nil
+}
+
+// parseMarkdownOutline 按标题层级和列表缩进解析需求大纲，代码块中的内容视为正文
+func parseMarkdownOutline(lines []string, start int) []markdownItem {
+	var items []markdownItem
+	var headings, bullets []markdownOpen
+	inFence := false
+	current := -1 // 当前正文归属的条目下标，-1表示正文不归属任何需求（如第一个标题之前的说明）
+	currentIndent := 0
+
+	for n := start; n < len(lines); n++ {
+		line := strings.ReplaceAll(lines[n], "\t", "    ")
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		// 代码块（含起止行）整体作为正文
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		text := inFence || fence
+		if fence {
+			inFence = !inFence
+		}
+
+		if !text {
+			if m := markdownHeading.FindStringSubmatch(line); m != nil {
+				level := len(m[1])
+				for len(headings) > 0 && headings[len(headings)-1].level >= level {
+					headings = headings[:len(headings)-1]
+				}
+				item := markdownItem{line: n + 1, typ: "requirement", title: m[2]}
+				if level == 1 {
+					item.typ = "epic"
+				}
+				if len(headings) > 0 {
+					item.parent = headings[len(headings)-1].row
+				}
+				items = append(items, item)
+				headings = append(headings, markdownOpen{level: level, row: len(items)})
+				bullets = nil
+				current, currentIndent = len(items)-1, 0
+				continue
+			}
+			if m := markdownBullet.FindStringSubmatch(trimmed); m != nil {
+				for len(bullets) > 0 && bullets[len(bullets)-1].level >= indent {
+					bullets = bullets[:len(bullets)-1]
+				}
+				item := markdownItem{line: n + 1, typ: "story", title: markdownTask.ReplaceAllString(m[2], "")}
+				switch {
+				case len(bullets) > 0:
+					item.parent = bullets[len(bullets)-1].row
+				case len(headings) > 0:
+					item.parent = headings[len(headings)-1].row
+				}
+				items = append(items, item)
+				bullets = append(bullets, markdownOpen{level: indent, row: len(items)})
+				current, currentIndent = len(items)-1, indent
+				continue
+			}
+			// 列表项的正文需要比列表项缩进更多：缩进较少的正文归属于外层列表项，顶格的正文结束列表，归属于所在的标题
+			if len(bullets) > 0 && trimmed != "" && indent <= currentIndent {
+				for len(bullets) > 0 && bullets[len(bullets)-1].level >= indent {
+					bullets = bullets[:len(bullets)-1]
+				}
+				current, currentIndent = -1, 0
+				if len(bullets) > 0 {
+					current, currentIndent = bullets[len(bullets)-1].row-1, bullets[len(bullets)-1].level
+				} else if len(headings) > 0 {
+					current = headings[len(headings)-1].row - 1
+				}
+			}
+		}
+
+		if current < 0 || (trimmed == "" && len(items[current].spec) == 0) {
+			continue
+		}
+		items[current].spec = append(items[current].spec, strings.TrimRight(dedent(line, currentIndent), " "))
+	}
+	return items
+}
+
+// dedent 去除列表项正文的缩进（最多去除列表项缩进+2个空格，保留更深的缩进）
+func dedent(line string, indent int) string {
+	width := indent + 2
+	n := 0
+	for n < len(line) && n < width && line[n] == ' ' {
+		n++
+	}
+	return line[n:]
+}
@@ -10,9 +10,31 @@ import (
 	"github.com/jan2xue/zentao_import_story/pkg/story"
 )
 
-// Reader 处理Excel文件的读取和验证
+// TemplateHeaders 导入模板的标题行（13列，也是未按标题定位时的固定列位置）
+var TemplateHeaders = []string{
+	"需求类型", "产品ID", "模块ID", "标题", "优先级", "分类", "需求描述",
+	"父需求ID", "来源", "来源备注", "预计工时", "关键词", "验收标准",
+}
+
+// 可选列标题（不在导入模板中）
+const (
+	HeaderExternalID = "外部ID"
+)
+
+// Reader 处理Excel文件的读取和验证，CSV/JSON/YAML 数据转换为内存中的单个工作表后同样由 Reader 读取（见 OpenStoryReader）
 type Reader struct {
-	file *excelize.File
+	file    workbook
+	source  *sourceRows       // 数据来自JSON/YAML/Markdown时校验错误的定位方式，为nil表示按Excel单元格定位
+	aliases map[string]string // 自定义列标题（字段名 -> 标题）
+	columns map[string]int    // 字段名 -> 列索引（0-based），由ReadStories根据标题行设置，为nil时使用固定列位置
+	header  []string          // 标题行，用于校验错误中显示列标题
+	sheets  []string          // 要读取的工作表（nil表示第一个工作表，AllSheets表示全部），见 SetSheets
+
+	// 当前正在读取的工作表，由 readSheet 设置
+	sheet     string            // 工作表名称（读取前为空）
+	headerRow int               // 标题行在工作表中的行号（1-based，未读取标题行时视为第1行）
+	offset    int               // 之前各工作表的数据行数之和，用于计算合并后的行号
+	defaults  map[string]string // 工作表标题区块中的默认值（字段名 -> 值），单元格为空时使用
 }
 
 // NewReader 创建新的Excel读取器
@@ -30,150 +52,354 @@ func (r *Reader) Close() error {
 }
 
 // ReadStories 读取层级需求数据
-// Excel列定义: 需求类型 | 产品ID | 模块ID | 标题 | 优先级 | 分类 | 需求描述 | 父需求ID | 来源 | 来源备注 | 预计工时 | 关键词 | 验收标准
-// 父需求ID支持格式: "@n"引用第n行数据的禅道ID，或纯数字作为实际禅道ID
+// 按标题行定位各列（支持中英文别名和config.yaml中的自定义标题），列顺序不限，多余的列会被忽略
+// 默认只读取第一个工作表，可通过 SetSheets 读取多个工作表，行号按工作表顺序连续编号
+// 父需求ID支持格式: "@n"引用本工作表第n行数据的禅道ID，"@工作表!n"引用其他工作表的行，或纯数字作为实际禅道ID
+// 数据有误时不会在第一行出错处停止，而是校验完全部行后返回 ValidationErrors
 func (r *Reader) ReadStories(defaultPriority int) ([]story.Story, error) {
-	sheets := r.file.GetSheetList()
-	if len(sheets) == 0 {
-		return nil, fmt.Errorf("Excel文件中没有工作表")
+	sheets, err := r.selectSheets()
+	if err != nil {
+		return nil, err
+	}
+
+	var stories []story.Story
+	var errs ValidationErrors
+	var infos []sheetInfo
+	for _, sheet := range sheets {
+		sheetStories, info, err := r.readSheet(sheet, defaultPriority)
+		if err != nil {
+			if sheetErrs, ok := err.(ValidationErrors); ok {
+				errs = append(errs, sheetErrs...)
+			} else {
+				return nil, err
+			}
+		}
+		if info.dataRows > 0 {
+			infos = append(infos, info)
+		}
+		stories = append(stories, sheetStories...)
+	}
+	if len(infos) == 0 && len(errs) == 0 {
+		return nil, fmt.Errorf("Excel文件中没有数据")
+	}
+	refErrs := append(resolveSheetRefs(stories, infos), resolveKeyRefs(stories, infos)...)
+	refErrs = append(refErrs, duplicateExternalIDs(stories, infos)...)
+	if len(r.sheets) == 0 {
+		// 与逐行校验错误一致：未指定工作表时错误位于第一个工作表，不显示工作表名称
+		for i := range refErrs {
+			refErrs[i].Sheet = ""
+		}
+	}
+	errs = append(errs, refErrs...)
+	if len(errs) > 0 {
+		if r.source != nil {
+			errs = r.source.locate(errs)
+		}
+		return nil, errs
 	}
 
-	rows, err := r.file.GetRows(sheets[0])
+	return stories, nil
+}
+
+// readSheet 读取一个工作表：定位标题行、解析标题行之前的默认值区块，再逐行解析数据
+// 读取全部工作表时，没有标题行或数据的工作表（如说明页）直接跳过
+func (r *Reader) readSheet(sheet string, defaultPriority int) ([]story.Story, sheetInfo, error) {
+	info := sheetInfo{name: sheet, offset: r.offset}
+
+	rows, err := r.file.GetRows(sheet)
 	if err != nil {
-		return nil, fmt.Errorf("读取工作表失败: %w", err)
+		return nil, info, fmt.Errorf("读取工作表 %s 失败: %w", sheet, err)
 	}
 
-	if len(rows) < 2 {
-		return nil, fmt.Errorf("Excel文件中没有数据")
+	headerIdx := findHeaderRow(rows, r.aliases)
+	if r.readAll() && (headerIdx < 0 || len(rows) < headerIdx+2) {
+		return nil, info, nil
+	}
+	if headerIdx < 0 {
+		headerIdx = 0 // 找不到标题行时按第一行处理，由 locateColumns 报告缺少的列
+	}
+	if len(rows) < headerIdx+2 {
+		return nil, info, r.sheetError(fmt.Errorf("Excel文件中没有数据"))
+	}
+	defaults := parseSheetDefaults(rows[:headerIdx])
+
+	columns, err := locateColumns(rows[headerIdx], r.aliases, defaults)
+	if err != nil {
+		return nil, info, r.sheetError(err)
 	}
+	r.columns = columns
+	r.header = rows[headerIdx]
+	r.sheet = sheet
+	r.headerRow = headerIdx + 1
+	r.defaults = defaults
 
 	var stories []story.Story
-	for i, row := range rows[1:] {
+	var errs ValidationErrors
+	for i, row := range rows[headerIdx+1:] {
 		s, err := r.parseRow(row, defaultPriority, i+1)
 		if err != nil {
-			return nil, fmt.Errorf("第%d行数据解析失败: %w", i+2, err)
+			if rowErrs, ok := err.(ValidationErrors); ok {
+				errs = append(errs, rowErrs...)
+				continue
+			}
+			return nil, info, fmt.Errorf("工作表 %s 第%d行数据解析失败: %w", sheet, r.headerRow+i+1, err)
 		}
 		stories = append(stories, s)
 	}
 
-	return stories, nil
+	info.dataRows = len(rows) - headerIdx - 1
+	info.headerRow = r.headerRow
+	info.parentCol, info.keyCol, info.externalCol = -1, -1, -1
+	if col, ok := columns[ColumnParent]; ok {
+		info.parentCol = col
+		info.parentHeader = strings.TrimSpace(r.header[col])
+	}
+	if col, ok := columns[ColumnRefKey]; ok {
+		info.keyCol = col
+		info.keyHeader = strings.TrimSpace(r.header[col])
+	}
+	if col, ok := columns[ColumnExternalID]; ok {
+		info.externalCol = col
+		info.externalHeader = strings.TrimSpace(r.header[col])
+	}
+	r.offset += info.dataRows
+	if len(errs) > 0 {
+		return stories, info, errs
+	}
+	return stories, info, nil
 }
 
-// parseRow 解析Excel行数据
-// Excel列定义: 需求类型 | 产品ID | 模块ID | 标题 | 优先级 | 分类 | 需求描述 | 父需求ID | 来源 | 来源备注 | 预计工时 | 关键词 | 验收标准
+// parseRow 解析Excel行数据，各字段所在的列由标题行决定（未读取标题行时使用模板的固定列位置）
+// 一行中的全部问题会一起以 ValidationErrors 返回
 func (r *Reader) parseRow(row []string, defaultPriority int, rowIndex int) (story.Story, error) {
-	if len(row) < 7 {
-		return story.Story{}, fmt.Errorf("行数据不完整，缺少必填字段，当前列数: %d", len(row))
+	sheetRow := rowIndex + max(r.headerRow, 1) // 数据行号1对应标题行的下一行
+	if r.columns == nil && len(row) < 7 {
+		return story.Story{}, ValidationErrors{{Sheet: r.errorSheet(), Row: sheetRow, Column: -1,
+			Message: fmt.Sprintf("行数据不完整，缺少必填字段，当前列数: %d", len(row))}}
+	}
+
+	var errs ValidationErrors
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, r.cellError(row, sheetRow, field, fmt.Sprintf(format, args...)))
 	}
 
-	// 解析需求类型 (第1列)
-	storyType := story.StoryTypeStory
-	switch strings.ToLower(strings.TrimSpace(row[0])) {
+	s := story.Story{RowIndex: r.offset + rowIndex, Sheet: r.sheet, SheetRow: sheetRow}
+
+	// 解析需求类型
+	switch strings.ToLower(r.cell(row, ColumnType)) {
 	case "epic":
-		storyType = story.StoryTypeEpic
+		s.Type = story.StoryTypeEpic
 	case "requirement":
-		storyType = story.StoryTypeRequirement
+		s.Type = story.StoryTypeRequirement
 	case "story":
-		storyType = story.StoryTypeStory
+		s.Type = story.StoryTypeStory
 	default:
-		return story.Story{}, fmt.Errorf("无效的需求类型: %s，支持: epic/requirement/story", strings.TrimSpace(row[0]))
+		fail(ColumnType, "无效的需求类型: %s，支持: epic/requirement/story", r.cell(row, ColumnType))
 	}
 
-	// 解析产品ID (第2列)
-	productID, err := strconv.Atoi(strings.TrimSpace(row[1]))
-	if err != nil {
-		return story.Story{}, fmt.Errorf("产品ID必须是数字: %w", err)
+	// 解析产品 - 填写数字时为产品ID，否则为产品名称或代号，导入前通过禅道产品列表解析为产品ID
+	if product := r.cell(row, ColumnProduct); product == "" {
+		fail(ColumnProduct, "产品不能为空，请填写产品ID、产品名称或代号")
+	} else if productID, err := strconv.Atoi(product); err != nil {
+		s.ProductRef = product
+	} else if productID <= 0 {
+		fail(ColumnProduct, "产品ID必须是正整数: %s", product)
+	} else {
+		s.ProductID = productID
 	}
 
-	// 解析模块ID (第3列) - 可选，空表示未指定将使用配置文件默认值，0为合法值表示不归属具体模块
-	moduleID := -1 // -1 表示Excel未填写
-	if len(row) > 2 && strings.TrimSpace(row[2]) != "" {
-		moduleID, err = strconv.Atoi(strings.TrimSpace(row[2]))
-		if err != nil {
-			return story.Story{}, fmt.Errorf("模块ID必须是数字: %w", err)
-		}
-		if moduleID < 0 {
-			return story.Story{}, fmt.Errorf("模块ID不能为负数: %d", moduleID)
+	// 解析模块 - 可选，空表示未指定将使用配置文件默认值，0为合法值表示不归属具体模块
+	// 填写数字时为模块ID，否则为模块路径（如 "支付/退款/对账"），导入前按产品模块树解析为模块ID
+	s.Module = -1 // -1 表示Excel未填写或填写了模块路径
+	if module := r.cell(row, ColumnModule); module != "" {
+		moduleID, err := strconv.Atoi(module)
+		switch {
+		case err != nil:
+			if s.ModulePath = story.NormalizeModulePath(module); s.ModulePath == "" {
+				fail(ColumnModule, "模块路径无效: %s", module)
+			}
+		case moduleID < 0:
+			fail(ColumnModule, "模块ID不能为负数: %d", moduleID)
+		default:
+			// moduleID >= 0 表示Excel显式指定了模块ID（0也是合法值）
+			s.Module = moduleID
 		}
-		// moduleID >= 0 表示Excel显式指定了模块ID（0也是合法值）
 	}
 
-	// 解析标题 (第4列)
-	title := strings.TrimSpace(row[3])
-	if title == "" {
-		return story.Story{}, fmt.Errorf("标题不能为空")
+	// 解析标题
+	if s.Title = r.cell(row, ColumnTitle); s.Title == "" {
+		fail(ColumnTitle, "标题不能为空")
 	}
 
-	// 解析优先级 (第5列)
-	priority := defaultPriority
-	if len(row) > 4 && strings.TrimSpace(row[4]) != "" {
-		p, err := strconv.Atoi(strings.TrimSpace(row[4]))
+	// 解析优先级
+	s.Priority = defaultPriority
+	if pri := r.cell(row, ColumnPriority); pri != "" {
+		p, err := strconv.Atoi(pri)
 		if err != nil || p < 1 || p > 4 {
-			return story.Story{}, fmt.Errorf("优先级必须是1-4之间的数字")
+			fail(ColumnPriority, "优先级必须是1-4之间的数字: %s", pri)
+		} else {
+			s.Priority = p
 		}
-		priority = p
 	}
 
-	// 解析分类 (第6列)
-	category := strings.TrimSpace(row[5])
-	if category == "" {
-		return story.Story{}, fmt.Errorf("分类不能为空")
+	// 解析分类
+	if s.Category = r.cell(row, ColumnCategory); s.Category == "" {
+		fail(ColumnCategory, "分类不能为空")
 	}
 
-	s := story.Story{
-		Type:      storyType,
-		Title:     title,
-		ProductID: productID,
-		Priority:  priority,
-		Category:  category,
-		Module:    moduleID,
-		RowIndex:  rowIndex,
+	// 解析需求描述
+	if s.Spec = r.cell(row, ColumnSpec); s.Spec == "" {
+		fail(ColumnSpec, "需求描述不能为空")
 	}
 
-	// 解析需求描述 (第7列)
-	if len(row) > 6 {
-		s.Spec = strings.TrimSpace(row[6])
+	// 解析父需求ID - 支持 "@n"、"@工作表!n"、"@引用键"、"#title:标题" 引用格式或纯数字禅道ID
+	if parentRef := r.cell(row, ColumnParent); parentRef != "" {
+		s.ParentRef = parentRef
+		if strings.HasPrefix(parentRef, "@") {
+			// "@n" 格式将在导入时解析，ParentID 暂为0；"@引用键" 在读取完全部行后改写为 "@n"
+			if _, isKeyRef := story.ParseKeyRef(parentRef); !isKeyRef {
+				if _, _, _, err := story.ParseSheetRowRef(parentRef); err != nil {
+					fail(ColumnParent, "父需求引用格式错误: %s，应为 \"@行号\"、\"@工作表!行号\"、\"@引用键\" 或禅道ID", parentRef)
+				}
+			}
+		} else if title, isTitleRef, err := story.ParseTitleRef(parentRef); isTitleRef {
+			// "#title:标题" 导入前在产品已有需求中按标题查找
+			if err != nil {
+				fail(ColumnParent, "%v", err)
+			}
+			s.ParentTitle = title
+		} else if id, err := strconv.Atoi(parentRef); err != nil || id <= 0 {
+			fail(ColumnParent, "父需求引用格式错误: %s，应为 \"@行号\"、\"@引用键\"、\"#title:标题\" 或禅道ID", parentRef)
+		} else {
+			s.ParentID = id
+		}
 	}
-	if s.Spec == "" {
-		return story.Story{}, fmt.Errorf("需求描述不能为空")
+
+	// 解析引用键 (可选列，其他行可用 "@引用键" 引用本行，插入或排序行后引用不变)
+	if s.RefKey = r.cell(row, ColumnRefKey); s.RefKey != "" {
+		if err := story.ValidateRefKey(s.RefKey); err != nil {
+			fail(ColumnRefKey, "%v", err)
+		}
 	}
 
-	// 解析父需求ID (第8列) - 支持 "@n" 引用格式
-	if len(row) > 7 {
-		if parentRef := strings.TrimSpace(row[7]); parentRef != "" {
-			s.ParentRef = parentRef
-			// 如果是纯数字，直接解析为禅道ID
-			if id, err := strconv.Atoi(parentRef); err == nil {
-				s.ParentID = id
-			}
-			// "@n" 格式将在导入时解析，ParentID 暂为0
+	s.Source = r.cell(row, ColumnSource)
+	s.SourceNote = r.cell(row, ColumnSourceNote)
+	// 解析预计工时
+	if estimate := r.cell(row, ColumnEstimate); estimate != "" {
+		est, err := strconv.ParseFloat(estimate, 64)
+		if err != nil || est < 0 {
+			fail(ColumnEstimate, "预计工时必须是非负数字: %s", estimate)
+		} else {
+			s.Estimate = est
 		}
 	}
+	s.Keywords = r.cell(row, ColumnKeywords)
+	s.Verify = r.cell(row, ColumnVerify)
 
-	// 解析来源 (第9列)
-	if len(row) > 8 {
-		s.Source = strings.TrimSpace(row[8])
+	// 解析指派给和评审人 (可选列，评审人可用逗号分隔填写多个账号)
+	if s.AssignedTo = r.cell(row, ColumnAssignedTo); len(splitAccounts(s.AssignedTo)) > 1 {
+		fail(ColumnAssignedTo, "指派给只能填写一个账号: %s", s.AssignedTo)
 	}
-	// 解析来源备注 (第10列)
-	if len(row) > 9 {
-		s.SourceNote = strings.TrimSpace(row[9])
+	s.Reviewers = splitAccounts(r.cell(row, ColumnReviewer))
+
+	// 解析项目/执行/计划 - 可选，填写数字时为ID，否则为名称，导入前通过禅道API解析并校验与产品的关联
+	parseLink := func(field, name string, id *int, ref *string) {
+		value := r.cell(row, field)
+		if value == "" {
+			return
+		}
+		n, err := strconv.Atoi(value)
+		switch {
+		case err != nil:
+			*ref = value
+		case n <= 0:
+			fail(field, "%sID必须是正整数: %s", name, value)
+		default:
+			*id = n
+		}
 	}
-	// 解析预计工时 (第11列)
-	if len(row) > 10 {
-		if estimate := strings.TrimSpace(row[10]); estimate != "" {
-			if est, err := strconv.ParseFloat(estimate, 64); err == nil {
-				s.Estimate = est
-			}
+	parseLink(ColumnProject, "项目", &s.Project, &s.ProjectRef)
+	parseLink(ColumnExecution, "执行", &s.Execution, &s.ExecutionRef)
+	parseLink(ColumnPlan, "计划", &s.Plan, &s.PlanRef)
+	if s.Type != "" && s.Type != story.StoryTypeStory {
+		if r.cell(row, ColumnProject) != "" {
+			fail(ColumnProject, "只有研发需求(story)可以关联项目")
+		}
+		if r.cell(row, ColumnExecution) != "" {
+			fail(ColumnExecution, "只有研发需求(story)可以关联执行")
 		}
 	}
-	// 解析关键词 (第12列)
-	if len(row) > 11 {
-		s.Keywords = strings.TrimSpace(row[11])
+	if s.Type == story.StoryTypeEpic && r.cell(row, ColumnPlan) != "" {
+		fail(ColumnPlan, "业务需求(epic)不能关联产品计划")
 	}
-	// 解析验收标准 (第13列)
-	if len(row) > 12 {
-		s.Verify = strings.TrimSpace(row[12])
+
+	// 解析外部ID (可选列)
+	s.ExternalID = r.cell(row, ColumnExternalID)
+
+	// 解析禅道ID (可选列，通常由回写功能填充)
+	if zentaoID := r.cell(row, ColumnZentaoID); zentaoID != "" {
+		id, err := strconv.Atoi(zentaoID)
+		if err != nil || id < 0 {
+			fail(ColumnZentaoID, "禅道ID必须是非负整数: %s", zentaoID)
+		} else {
+			s.ZentaoID = id
+		}
 	}
 
+	if len(errs) > 0 {
+		return story.Story{}, errs
+	}
 	return s, nil
 }
+
+// splitAccounts 拆分逗号（半角/全角）、顿号或分号分隔的账号列表，去除空白和重复项
+func splitAccounts(value string) []string {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ';' || r == '；'
+	})
+	var accounts []string
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" && !seen[f] {
+			seen[f] = true
+			accounts = append(accounts, f)
+		}
+	}
+	return accounts
+}
+
+// cellError 构造字段对应单元格的校验错误
+func (r *Reader) cellError(row []string, sheetRow int, field, message string) CellError {
+	col, ok := r.columnIndex(field)
+	if !ok {
+		return CellError{Sheet: r.errorSheet(), Row: sheetRow, Column: -1, Message: message}
+	}
+	header := ""
+	if col < len(r.header) {
+		header = strings.TrimSpace(r.header[col])
+	} else if col < len(TemplateHeaders) {
+		header = TemplateHeaders[col]
+	}
+	return CellError{Sheet: r.errorSheet(), Row: sheetRow, Column: col, Header: header, Value: r.cell(row, field), Message: message}
+}
+
+// columnIndex 返回字段所在的列索引
+func (r *Reader) columnIndex(field string) (int, bool) {
+	columns := r.columns
+	if columns == nil {
+		columns = legacyColumns
+	}
+	col, ok := columns[field]
+	return col, ok
+}
+
+// cell 读取字段对应单元格的值（已去除首尾空白），单元格为空时使用工作表默认值，列不存在或超出行长度时返回空字符串
+func (r *Reader) cell(row []string, field string) string {
+	col, ok := r.columnIndex(field)
+	if ok && col < len(row) {
+		if value := strings.TrimSpace(row[col]); value != "" {
+			return value
+		}
+	}
+	return r.defaults[field]
+}
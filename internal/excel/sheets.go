@@ -0,0 +1,226 @@
+// Package excel 处理Excel文件的读写操作 - 多工作表读取与跨工作表引用
+package excel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// AllSheets SetSheets 的特殊值：读取全部工作表
+const AllSheets = "*"
+
+// sheetDefaultKeys 标题行之前默认值区块的键（第一列）对应的字段，第二列为默认值
+var sheetDefaultKeys = map[string]string{
+	"默认产品":            ColumnProduct,
+	"default product": ColumnProduct,
+	"默认模块":            ColumnModule,
+	"default module":  ColumnModule,
+}
+
+// sheetInfo 已读取工作表的行号信息，用于解析 "@行号"、"@工作表!行号" 和 "@引用键" 引用
+type sheetInfo struct {
+	name           string
+	offset         int    // 之前各工作表的数据行数之和
+	dataRows       int    // 数据行数
+	headerRow      int    // 标题行在工作表中的行号（1-based）
+	parentCol      int    // 父需求列索引（-1表示没有该列）
+	parentHeader   string // 父需求列标题
+	keyCol         int    // 引用键列索引（-1表示没有该列）
+	keyHeader      string // 引用键列标题
+	externalCol    int    // 外部ID列索引（-1表示没有该列）
+	externalHeader string // 外部ID列标题
+}
+
+// SetSheets 设置要读取的工作表：nil 表示只读取第一个工作表（默认），[]string{AllSheets} 表示按顺序读取全部工作表
+// 指定的工作表不存在时 ReadStories 返回错误
+func (r *Reader) SetSheets(names []string) {
+	r.sheets = names
+}
+
+// readAll 是否读取全部工作表
+func (r *Reader) readAll() bool {
+	return len(r.sheets) == 1 && r.sheets[0] == AllSheets
+}
+
+// selectSheets 返回要读取的工作表名称
+func (r *Reader) selectSheets() ([]string, error) {
+	available := r.file.GetSheetList()
+	if len(available) == 0 {
+		return nil, fmt.Errorf("Excel文件中没有工作表")
+	}
+	switch {
+	case len(r.sheets) == 0:
+		return available[:1], nil
+	case r.readAll():
+		return available, nil
+	}
+
+	exists := make(map[string]bool, len(available))
+	for _, name := range available {
+		exists[name] = true
+	}
+	var missing []string
+	for _, name := range r.sheets {
+		if !exists[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("工作表不存在: %s，可选: %s", strings.Join(missing, "、"), strings.Join(available, "、"))
+	}
+	return r.sheets, nil
+}
+
+// sheetError 指定了工作表时在错误前加上当前工作表名称
+func (r *Reader) sheetError(err error) error {
+	if len(r.sheets) == 0 {
+		return err
+	}
+	return fmt.Errorf("工作表 %s: %w", r.sheet, err)
+}
+
+// errorSheet 校验错误中显示的工作表名称（未指定工作表时为空，错误位于第一个工作表）
+func (r *Reader) errorSheet() string {
+	if len(r.sheets) == 0 {
+		return ""
+	}
+	return r.sheet
+}
+
+// findHeaderRow 查找标题行：第一个同时包含需求类型列和标题列的行（0-based），找不到时返回-1
+// 标题行之前的行为默认值区块（见 parseSheetDefaults）或说明文字
+func findHeaderRow(rows [][]string, custom map[string]string) int {
+	for idx, row := range rows {
+		_, hasType := findHeader(row, fieldAliases(ColumnType, custom))
+		_, hasTitle := findHeader(row, fieldAliases(ColumnTitle, custom))
+		if hasType && hasTitle {
+			return idx
+		}
+	}
+	return -1
+}
+
+// fieldAliases 返回字段可识别的标题，自定义标题优先
+func fieldAliases(field string, custom map[string]string) []string {
+	aliases := findColumnDef(field).aliases
+	if name := strings.TrimSpace(custom[field]); name != "" {
+		aliases = append([]string{name}, aliases...)
+	}
+	return aliases
+}
+
+// parseSheetDefaults 解析标题行之前的默认值区块，如第一列 "默认产品"、第二列 "78"
+// 默认产品/默认模块用于该工作表中产品列/模块列为空（或没有该列）的行，无法识别的行会被忽略
+func parseSheetDefaults(rows [][]string) map[string]string {
+	defaults := make(map[string]string)
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(row[0]))
+		key = strings.TrimRight(key, ":：")
+		if field, ok := sheetDefaultKeys[key]; ok {
+			if value := strings.TrimSpace(row[1]); value != "" {
+				defaults[field] = value
+			}
+		}
+	}
+	return defaults
+}
+
+// resolveSheetRefs 将 "@行号"（本工作表）和 "@工作表!行号" 引用改写为合并后的行号 "@n"，返回无法解析的引用
+// 只读取一个工作表且引用本工作表时行号不变，引用不存在的行留给 story.ValidateReferences 报告
+func resolveSheetRefs(stories []story.Story, infos []sheetInfo) ValidationErrors {
+	byName := make(map[string]sheetInfo, len(infos))
+	for _, info := range infos {
+		byName[info.name] = info
+	}
+
+	var errs ValidationErrors
+	for idx := range stories {
+		s := &stories[idx]
+		sheet, row, isRowRef, err := story.ParseSheetRowRef(s.ParentRef)
+		if !isRowRef || err != nil {
+			continue
+		}
+		current := byName[s.Sheet]
+		fail := func(message string) {
+			errs = append(errs, CellError{Sheet: s.Sheet, Row: s.SheetRow, Column: current.parentCol,
+				Header: current.parentHeader, Value: s.ParentRef, Message: message})
+		}
+
+		if sheet == "" {
+			if len(infos) > 1 && row > current.dataRows {
+				fail(fmt.Sprintf("父需求引用 %s 指向的行不存在（工作表 %s 共 %d 行数据）", s.ParentRef, s.Sheet, current.dataRows))
+				continue
+			}
+			s.ParentRef = "@" + strconv.Itoa(current.offset+row)
+			continue
+		}
+
+		target, ok := byName[sheet]
+		switch {
+		case !ok:
+			fail(fmt.Sprintf("父需求引用 %s 指向的工作表 %s 不存在或未读取（可使用 -sheets 指定要读取的工作表）", s.ParentRef, sheet))
+		case row > target.dataRows:
+			fail(fmt.Sprintf("父需求引用 %s 指向的行不存在（工作表 %s 共 %d 行数据）", s.ParentRef, sheet, target.dataRows))
+		default:
+			s.ParentRef = "@" + strconv.Itoa(target.offset+row)
+		}
+	}
+	return errs
+}
+
+// resolveKeyRefs 将 "@引用键" 引用改写为合并后的行号 "@n"（引用键在读取的全部工作表中唯一，见 story.ResolveKeyRefs）
+// 重复的引用键标注在引用键列，不存在或重复的引用标注在父需求列
+func resolveKeyRefs(stories []story.Story, infos []sheetInfo) ValidationErrors {
+	byName := make(map[string]sheetInfo, len(infos))
+	for _, info := range infos {
+		byName[info.name] = info
+	}
+	byRow := make(map[int]*story.Story, len(stories))
+	for idx := range stories {
+		byRow[stories[idx].RowIndex] = &stories[idx]
+	}
+
+	var errs ValidationErrors
+	for _, issue := range story.ResolveKeyRefs(stories) {
+		s := byRow[issue.RowIndex]
+		info := byName[s.Sheet]
+		cell := CellError{Sheet: s.Sheet, Row: s.SheetRow, Column: info.parentCol, Header: info.parentHeader,
+			Value: s.ParentRef, Message: issue.Message}
+		if issue.Field == story.IssueFieldRefKey {
+			cell.Column, cell.Header, cell.Value = info.keyCol, info.keyHeader, s.RefKey
+		}
+		errs = append(errs, cell)
+	}
+	return errs
+}
+
+// duplicateExternalIDs 报告在读取的全部工作表中重复的外部ID（标注在外部ID列）
+// 外部ID是幂等导入匹配已导入需求的唯一键，重复时后一行会更新前一行刚创建的需求
+func duplicateExternalIDs(stories []story.Story, infos []sheetInfo) ValidationErrors {
+	byName := make(map[string]sheetInfo, len(infos))
+	for _, info := range infos {
+		byName[info.name] = info
+	}
+	count := make(map[string]int)
+	for _, s := range stories {
+		if s.ExternalID != "" {
+			count[s.ExternalID]++
+		}
+	}
+
+	var errs ValidationErrors
+	for _, s := range stories {
+		if n := count[s.ExternalID]; s.ExternalID != "" && n > 1 {
+			info := byName[s.Sheet]
+			errs = append(errs, CellError{Sheet: s.Sheet, Row: s.SheetRow, Column: info.externalCol, Header: info.externalHeader,
+				Value: s.ExternalID, Message: fmt.Sprintf("外部ID %s 重复，共 %d 行使用该外部ID", s.ExternalID, n)})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,282 @@
+// Package excel 处理Excel文件的读写操作 - 需求数据源（Excel、CSV、JSON、YAML、Markdown）
+package excel
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"gopkg.in/yaml.v3"
+)
+
+// 需求数据文件格式
+const (
+	FormatExcel    = "excel"
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatMarkdown = "markdown"
+)
+
+// StoryReader 需求数据读取器，各格式共用相同的字段校验规则和父需求引用规则
+type StoryReader interface {
+	// SetColumnAliases 设置自定义列标题（JSON/YAML 中为字段名）
+	SetColumnAliases(aliases map[string]string) error
+	// ReadStories 读取全部需求，校验失败时返回 ValidationErrors
+	ReadStories(defaultPriority int) ([]story.Story, error)
+	// Close 释放文件
+	Close() error
+}
+
+// workbook Reader 读取的数据表：Excel文件，或由CSV/JSON/YAML/Markdown转换得到的内存数据表
+type workbook interface {
+	GetSheetList() []string
+	GetRows(sheet string, opts ...excelize.Options) ([][]string, error)
+	Close() error
+}
+
+// memoryWorkbook 只有一个工作表的内存数据表
+type memoryWorkbook struct {
+	name string
+	rows [][]string
+}
+
+// GetSheetList 返回唯一的工作表名称
+func (m *memoryWorkbook) GetSheetList() []string { return []string{m.name} }
+
+// GetRows 返回工作表的全部行
+func (m *memoryWorkbook) GetRows(sheet string, _ ...excelize.Options) ([][]string, error) {
+	if sheet != m.name {
+		return nil, fmt.Errorf("工作表 %s 不存在", sheet)
+	}
+	return m.rows, nil
+}
+
+// Close 内存数据表无需释放
+func (m *memoryWorkbook) Close() error { return nil }
+
+// DetectFormat 确定数据文件格式：format 非空时使用指定格式（excel/xlsx、csv、json、yaml/yml、markdown/md），否则按扩展名判断
+// 无法识别的扩展名按Excel处理
+func DetectFormat(path, format string) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(format))
+	if value == "" {
+		value = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch value {
+	case FormatCSV:
+		return FormatCSV, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatYAML, "yml":
+		return FormatYAML, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatExcel, "xlsx", "xlsm", "xltx", "xltm":
+		return FormatExcel, nil
+	}
+	if format != "" {
+		return "", fmt.Errorf("不支持的数据格式: %s，支持: excel、csv、json、yaml、markdown", format)
+	}
+	return FormatExcel, nil
+}
+
+// OpenStoryReader 按格式打开需求数据文件（format 为空时按扩展名判断）
+// sheets 为要读取的工作表（见 Reader.SetSheets），仅适用于Excel文件
+func OpenStoryReader(path, format string, sheets []string) (StoryReader, error) {
+	format, err := DetectFormat(path, format)
+	if err != nil {
+		return nil, err
+	}
+	if format != FormatExcel && len(sheets) > 0 {
+		return nil, fmt.Errorf("工作表选择（sheets）仅适用于Excel文件，当前文件格式为 %s", format)
+	}
+
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(path)
+	case FormatJSON:
+		return NewJSONReader(path)
+	case FormatYAML:
+		return NewYAMLReader(path)
+	case FormatMarkdown:
+		return NewMarkdownReader(path)
+	}
+	reader, err := NewReader(path)
+	if err != nil {
+		return nil, err
+	}
+	reader.SetSheets(sheets)
+	return reader, nil
+}
+
+// NewCSVReader 创建CSV文件读取器，文件编码为UTF-8（可带BOM）或GBK，自动识别
+// 标题行及之前的默认值区块与Excel工作表相同，校验错误中的行号为CSV文件行号
+func NewCSVReader(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开CSV文件失败: %w", err)
+	}
+	data, err = decodeText(data)
+	if err != nil {
+		return nil, fmt.Errorf("CSV文件编码转换失败: %w", err)
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1 // 默认值区块与数据行的列数不同
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV文件失败: %w", err)
+	}
+	return &Reader{file: &memoryWorkbook{name: sourceName(path), rows: rows}}, nil
+}
+
+// decodeText 去除UTF-8 BOM；内容不是合法的UTF-8时按GBK解码（Windows版Excel另存的CSV）
+func decodeText(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if utf8.Valid(data) {
+		return data, nil
+	}
+	return simplifiedchinese.GBK.NewDecoder().Bytes(data)
+}
+
+// NewJSONReader 创建JSON文件读取器
+// 文件内容为需求对象数组，或 {"stories": [...]}；对象的键为字段名（如 title）或列标题（如 "标题"）
+func NewJSONReader(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开JSON文件失败: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // 保留数字原文，如产品ID 78 不会变为 78.0
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("解析JSON文件失败: %w", err)
+	}
+	return newRecordReader(path, doc)
+}
+
+// NewYAMLReader 创建YAML文件读取器，结构与JSON相同：需求列表，或 stories 键下的需求列表
+func NewYAMLReader(path string) (*Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开YAML文件失败: %w", err)
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析YAML文件失败: %w", err)
+	}
+	return newRecordReader(path, doc)
+}
+
+// newRecordReader 将需求记录列表转换为数据表：标题行为全部记录中出现过的键，每条记录一行
+func newRecordReader(path string, doc interface{}) (*Reader, error) {
+	if m, ok := doc.(map[string]interface{}); ok {
+		doc = m["stories"]
+	}
+	items, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("数据格式错误：应为需求列表，或 stories 键下的需求列表")
+	}
+
+	records := make([]map[string]string, len(items))
+	keys := make(map[string]bool)
+	for n, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("第%d条记录格式错误：应为键值对象", n+1)
+		}
+		records[n] = make(map[string]string, len(fields))
+		for key, value := range fields {
+			text, err := recordValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("第%d条记录 字段[%s]: %w", n+1, key, err)
+			}
+			records[n][key] = text
+			keys[key] = true
+		}
+	}
+
+	header := make([]string, 0, len(keys))
+	for key := range keys {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+	rows := [][]string{header}
+	for _, record := range records {
+		row := make([]string, len(header))
+		for i, key := range header {
+			row[i] = record[key]
+		}
+		rows = append(rows, row)
+	}
+	return &Reader{file: &memoryWorkbook{name: sourceName(path), rows: rows}, source: &sourceRows{unit: "条记录"}}, nil
+}
+
+// recordValue 将记录字段值转换为单元格文本，列表（如多个评审人）以逗号连接
+func recordValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			text, err := recordValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, text)
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("不支持的值类型 %T，应为文本、数字或文本列表", value)
+}
+
+// sourceRows 非Excel数据源中数据行的定位方式
+type sourceRows struct {
+	unit  string // 行号单位，见 CellError.Unit
+	lines []int  // 第n个数据行对应的源文件行号（下标n-1），为nil时使用数据行序号
+}
+
+// locate 将校验错误中的工作表行号换算为数据源中的位置（第1个数据行位于标题行之后的第2行）
+func (s *sourceRows) locate(errs ValidationErrors) ValidationErrors {
+	for i := range errs {
+		errs[i].Unit = s.unit
+		row := errs[i].Row - 1
+		if row >= 1 && row <= len(s.lines) {
+			row = s.lines[row-1]
+		}
+		errs[i].Row = row
+	}
+	return errs
+}
+
+// sourceName 内存数据表的名称（不含扩展名的文件名）
+func sourceName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
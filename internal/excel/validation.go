@@ -0,0 +1,124 @@
+// Package excel 处理Excel文件的读写操作 - 数据校验错误汇总
+package excel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// CellError 单元格级的校验错误
+type CellError struct {
+	Sheet   string // 工作表名称（为空表示第一个工作表）
+	Row     int    // 工作表行号（标题行为第1行）
+	Column  int    // 列索引（0-based），-1 表示整行问题
+	Header  string // 列标题（整行问题时为空）
+	Value   string // 单元格原值
+	Message string // 错误原因
+	Unit    string // 非Excel数据源的行号单位："条记录"（JSON/YAML，Row为记录序号）或 "行"（Markdown，Row为源文件行号），此时Header为字段名
+}
+
+// Error 实现 error 接口
+func (e CellError) Error() string {
+	prefix := ""
+	if e.Sheet != "" {
+		prefix = fmt.Sprintf("工作表 %s ", e.Sheet)
+	}
+	switch {
+	case e.Unit != "" && e.Column < 0:
+		return fmt.Sprintf("第%d%s: %s", e.Row, e.Unit, e.Message)
+	case e.Unit != "":
+		return fmt.Sprintf("第%d%s 字段[%s]: %s", e.Row, e.Unit, e.Header, e.Message)
+	case e.Column < 0:
+		return fmt.Sprintf("%s第%d行: %s", prefix, e.Row, e.Message)
+	}
+	return fmt.Sprintf("%s第%d行 %s列[%s]: %s", prefix, e.Row, columnName(e.Column), e.Header, e.Message)
+}
+
+// ValidationErrors 读取Excel时发现的全部校验错误
+type ValidationErrors []CellError
+
+// Error 实现 error 接口，逐行列出全部错误
+func (v ValidationErrors) Error() string {
+	lines := make([]string, 0, len(v)+1)
+	lines = append(lines, fmt.Sprintf("Excel数据校验失败，共 %d 个问题:", len(v)))
+	for _, e := range v {
+		lines = append(lines, "  "+e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExportValidationReport 将校验错误标注到源文件副本：错误单元格标红并添加批注，整行问题标注在该行第一列
+// 源文件不会被修改
+func ExportValidationReport(srcPath, outputPath string, errs ValidationErrors) error {
+	f, err := excelize.OpenFile(srcPath)
+	if err != nil {
+		return fmt.Errorf("打开Excel文件失败: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return fmt.Errorf("Excel文件中没有工作表")
+	}
+
+	style, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+		Font: &excelize.Font{Color: "9C0006"},
+	})
+	if err != nil {
+		return fmt.Errorf("创建单元格样式失败: %w", err)
+	}
+
+	// 同一单元格的多个错误合并为一条批注，未指定工作表的错误标注在第一个工作表
+	type sheetCell struct{ sheet, cell string }
+	var cells []sheetCell
+	messages := make(map[sheetCell][]string)
+	for _, e := range errs {
+		col := e.Column
+		if col < 0 {
+			col = 0
+		}
+		cell, err := excelize.CoordinatesToCellName(col+1, e.Row)
+		if err != nil {
+			return fmt.Errorf("计算单元格坐标失败: %w", err)
+		}
+		key := sheetCell{sheet: e.Sheet, cell: cell}
+		if key.sheet == "" {
+			key.sheet = sheets[0]
+		}
+		if _, ok := messages[key]; !ok {
+			cells = append(cells, key)
+		}
+		messages[key] = append(messages[key], e.Message)
+	}
+
+	for _, key := range cells {
+		sheet, cell := key.sheet, key.cell
+		if err := f.SetCellStyle(sheet, cell, cell, style); err != nil {
+			return fmt.Errorf("设置单元格%s样式失败: %w", cell, err)
+		}
+		if err := f.AddComment(sheet, excelize.Comment{
+			Cell:   cell,
+			Author: "校验",
+			Text:   strings.Join(messages[key], "\n"),
+		}); err != nil {
+			return fmt.Errorf("添加单元格%s批注失败: %w", cell, err)
+		}
+	}
+
+	if err := f.SaveAs(outputPath); err != nil {
+		return fmt.Errorf("保存Excel文件失败: %w", err)
+	}
+	return nil
+}
+
+// columnName 将列索引（0-based）转换为列名，如 0 -> A
+func columnName(col int) string {
+	name, err := excelize.ColumnNumberToName(col + 1)
+	if err != nil {
+		return fmt.Sprintf("%d", col+1)
+	}
+	return name
+}
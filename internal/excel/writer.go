@@ -0,0 +1,244 @@
+// Package excel 处理Excel文件的读写操作
+package excel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+	"github.com/xuri/excelize/v2"
+)
+
+// 回写列标题
+const (
+	HeaderZentaoID     = "禅道ID"
+	HeaderImportStatus = "导入状态"
+	HeaderErrorMessage = "错误信息"
+)
+
+// RowResult 单行导入结果（用于回写Excel）
+type RowResult struct {
+	RowIndex int    // 数据行号（1-based，不含标题行），与 story.Story.RowIndex 一致
+	Sheet    string // 工作表名称（为空表示第一个工作表），与 story.Story.Sheet 一致
+	SheetRow int    // 工作表行号，与 story.Story.SheetRow 一致（为0时按标题行为第1行由RowIndex计算）
+	ID       int    // 禅道ID（0表示未创建）
+	Status   string // 导入状态
+	Message  string // 错误信息
+}
+
+// Writer 将导入结果回写到Excel源文件
+type Writer struct {
+	file     *excelize.File
+	filePath string
+}
+
+// NewWriter 打开需要回写的Excel文件
+func NewWriter(filePath string) (*Writer, error) {
+	f, err := excelize.OpenFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("打开Excel文件失败: %w", err)
+	}
+	return &Writer{file: f, filePath: filePath}, nil
+}
+
+// Close 关闭Excel文件
+func (w *Writer) Close() error {
+	return w.file.Close()
+}
+
+// WriteResults 将导入结果按行写回所在的工作表（未指定工作表时为第一个工作表）
+// "禅道ID"/"导入状态"/"错误信息"列已存在时直接填充，不存在时追加到最后一列之后
+func (w *Writer) WriteResults(results []RowResult) error {
+	sheets := w.file.GetSheetList()
+	if len(sheets) == 0 {
+		return fmt.Errorf("Excel文件中没有工作表")
+	}
+
+	var order []string
+	bySheet := make(map[string][]RowResult)
+	for _, result := range results {
+		sheet := result.Sheet
+		if sheet == "" {
+			sheet = sheets[0]
+		}
+		if result.SheetRow == 0 {
+			// 数据行号1对应工作表第2行（第1行为标题行）
+			result.SheetRow = result.RowIndex + 1
+		}
+		if _, ok := bySheet[sheet]; !ok {
+			order = append(order, sheet)
+		}
+		bySheet[sheet] = append(bySheet[sheet], result)
+	}
+
+	for _, sheet := range order {
+		if err := w.writeSheetResults(sheet, bySheet[sheet]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSheetResults 将导入结果写入一个工作表，标题行为第一条数据行的上一行
+func (w *Writer) writeSheetResults(sheet string, results []RowResult) error {
+	rows, err := w.file.GetRows(sheet)
+	if err != nil {
+		return fmt.Errorf("读取工作表 %s 失败: %w", sheet, err)
+	}
+	headerRow := results[0].SheetRow - 1
+	for _, result := range results {
+		headerRow = min(headerRow, result.SheetRow-1)
+	}
+	var header []string
+	if headerRow >= 1 && headerRow <= len(rows) {
+		header = rows[headerRow-1]
+	}
+	// 追加列放在所有行中最右侧已用列之后，避免覆盖没有标题的数据列
+	lastCol := 0
+	for _, row := range rows {
+		if len(row) > lastCol {
+			lastCol = len(row)
+		}
+	}
+
+	idCol, err := w.ensureColumn(sheet, headerRow, header, &lastCol, HeaderZentaoID, ColumnZentaoID)
+	if err != nil {
+		return err
+	}
+	statusCol, err := w.ensureColumn(sheet, headerRow, header, &lastCol, HeaderImportStatus, "")
+	if err != nil {
+		return err
+	}
+	msgCol, err := w.ensureColumn(sheet, headerRow, header, &lastCol, HeaderErrorMessage, "")
+	if err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		var id interface{} = ""
+		if result.ID > 0 {
+			id = result.ID
+		}
+		if err := w.setCell(sheet, idCol, result.SheetRow, id); err != nil {
+			return err
+		}
+		if err := w.setCell(sheet, statusCol, result.SheetRow, result.Status); err != nil {
+			return err
+		}
+		if err := w.setCell(sheet, msgCol, result.SheetRow, result.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save 保存回写结果，outputPath 为空时覆盖原文件
+func (w *Writer) Save(outputPath string) error {
+	if outputPath == "" {
+		outputPath = w.filePath
+	}
+	if err := w.file.SaveAs(outputPath); err != nil {
+		return fmt.Errorf("保存Excel文件失败: %w", err)
+	}
+	return nil
+}
+
+// ensureColumn 返回标题为name（或字段field的别名）的列号（1-based），不存在时在标题行(headerRow)追加到lastCol之后并更新lastCol
+func (w *Writer) ensureColumn(sheet string, headerRow int, header []string, lastCol *int, name, field string) (int, error) {
+	for i, h := range header {
+		if strings.TrimSpace(h) == name || (field != "" && IsHeader(field, h)) {
+			return i + 1, nil
+		}
+	}
+	*lastCol++
+	if err := w.setCell(sheet, *lastCol, max(headerRow, 1), name); err != nil {
+		return 0, err
+	}
+	return *lastCol, nil
+}
+
+// setCell 按行列号写入单元格
+func (w *Writer) setCell(sheet string, col, row int, value interface{}) error {
+	cell, err := excelize.CoordinatesToCellName(col, row)
+	if err != nil {
+		return fmt.Errorf("计算单元格坐标失败: %w", err)
+	}
+	if err := w.file.SetCellValue(sheet, cell, value); err != nil {
+		return fmt.Errorf("写入单元格%s失败: %w", cell, err)
+	}
+	return nil
+}
+
+// ExportStories 按导入模板的列格式（TemplateHeaders）将需求写入新的Excel文件
+// 导出的文件可直接被 Reader.ReadStories 读取；填写了 ModulePath 的需求在模块列写入路径，否则写入模块ID
+func ExportStories(filePath string, stories []story.Story) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	header := make([]interface{}, len(TemplateHeaders))
+	for i, h := range TemplateHeaders {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return fmt.Errorf("写入标题行失败: %w", err)
+	}
+
+	for _, s := range stories {
+		module := s.ModulePath
+		if module == "" && s.Module >= 0 {
+			module = strconv.Itoa(s.Module)
+		}
+		priority := ""
+		if s.Priority > 0 {
+			priority = strconv.Itoa(s.Priority)
+		}
+		estimate := ""
+		if s.Estimate != 0 {
+			estimate = strconv.FormatFloat(s.Estimate, 'f', -1, 64)
+		}
+		row := []interface{}{
+			string(s.Type), s.ProductID, module, s.Title, priority, s.Category, s.Spec,
+			s.ParentRef, s.Source, s.SourceNote, estimate, s.Keywords, s.Verify,
+		}
+		cell, err := excelize.CoordinatesToCellName(1, s.RowIndex+1)
+		if err != nil {
+			return fmt.Errorf("计算单元格坐标失败: %w", err)
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return fmt.Errorf("写入第%d行失败: %w", s.RowIndex, err)
+		}
+	}
+
+	if err := f.SaveAs(filePath); err != nil {
+		return fmt.Errorf("保存Excel文件失败: %w", err)
+	}
+	return nil
+}
+
+// WriteTable 将表格数据写入新的Excel文件（第一行为标题行），用于导出比对结果等报表
+func WriteTable(filePath string, headers []string, rows [][]string) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetName(0)
+	for r, values := range append([][]string{headers}, rows...) {
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		cell, err := excelize.CoordinatesToCellName(1, r+1)
+		if err != nil {
+			return fmt.Errorf("计算单元格坐标失败: %w", err)
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return fmt.Errorf("写入第%d行失败: %w", r+1, err)
+		}
+	}
+
+	if err := f.SaveAs(filePath); err != nil {
+		return fmt.Errorf("保存Excel文件失败: %w", err)
+	}
+	return nil
+}
@@ -34,6 +34,9 @@ type Client struct {
 	Requirement *RequirementService
 	Story       *StoryService
 	Product     *ProductService
+	User        *UserService
+	Module      *ModuleService
+	Project     *ProjectService
 }
 
 // NewClient 创建新的禅道客户端
@@ -91,6 +94,9 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	c.Requirement = NewRequirementService(c)
 	c.Story = NewStoryService(c)
 	c.Product = NewProductService(c)
+	c.User = NewUserService(c)
+	c.Module = NewModuleService(c)
+	c.Project = NewProjectService(c)
 
 	return c, nil
 }
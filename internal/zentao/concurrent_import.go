@@ -0,0 +1,46 @@
+// Package zentao 封装禅道API客户端 - 同一层级内的并发导入
+package zentao
+
+import "sync"
+
+// maxImportConcurrency 导入并发数上限（避免对禅道服务器造成过大压力）
+const maxImportConcurrency = 10
+
+// SetConcurrency 设置同一层级内的并发导入数（<=1 为顺序导入，最大为10）
+// 各层级仍依次导入（Epic → Requirement → Story），只有同一层级内的需求并发创建
+func (i *Importer) SetConcurrency(n int) {
+	if n > maxImportConcurrency {
+		n = maxImportConcurrency
+	}
+	i.concurrency = n
+}
+
+// importLevel 导入同一层级的需求，结果按 stories 下标写入，与完成顺序无关
+// 同一层级（类型 + 子需求层级）内的需求互不为父需求：父需求总在更早的层级中，
+// 调用方在上一层级全部完成（importLevel 返回）后才开始下一层级，因此并发创建时父需求ID均已解析
+func (i *Importer) importLevel(run *importRun, idxs []int) {
+	if i.concurrency <= 1 || len(idxs) <= 1 {
+		for _, idx := range idxs {
+			i.importAt(run, idx)
+		}
+		return
+	}
+
+	i.logger.Info("并发导入 %d 个需求，并发数: %d", len(idxs), i.concurrency)
+
+	// 用信号量控制并发数
+	sem := make(chan struct{}, i.concurrency)
+	var wg sync.WaitGroup
+
+	for _, idx := range idxs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			i.importAt(run, idx)
+		}(idx)
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,135 @@
+package zentao
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestImporter_ImportStories_Concurrent(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	nextID := 800
+	parents := make(map[string]int) // Story标题 -> 创建请求中的父需求ID
+	reqCreated := false
+
+	mockEpic := &mockEpicService{
+		listFn: func(productID int) ([]EpicListItem, error) { return nil, nil },
+	}
+	mockReq := &mockReqService{
+		createFn: func(req RequirementCreateRequest) (*RequirementCreateResponse, *req.Response, error) {
+			reqCreated = true
+			return &RequirementCreateResponse{Status: "success"}, nil, nil
+		},
+		listFn: func(productID int) ([]RequirementListItem, error) {
+			if !reqCreated {
+				return nil, nil
+			}
+			return []RequirementListItem{{ID: 501, Title: "R"}}, nil
+		},
+	}
+	mockStorySvc := &mockStoryService{
+		createFn: func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			inFlight--
+			nextID++
+			parents[req.Title] = req.Parent
+			return &StoryCreateResponse{Status: "success", ID: nextID}, nil, nil
+		},
+		listFn: func(productID int) ([]StoryListItem, error) { return nil, nil },
+	}
+
+	importer := NewImporterWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{})
+	importer.SetConcurrency(3)
+
+	stories := []story.Story{
+		{Type: story.StoryTypeRequirement, Title: "R", ProductID: 1, RowIndex: 1},
+	}
+	for n := 0; n < 6; n++ {
+		stories = append(stories, story.Story{
+			Type: story.StoryTypeStory, Title: string(rune('A' + n)), ProductID: 1, ParentRef: "@1", RowIndex: n + 2,
+		})
+	}
+
+	results := importer.ImportStories(stories)
+
+	if maxInFlight < 2 || maxInFlight > 3 {
+		t.Errorf("同时创建的研发需求数 = %d, want 2~3", maxInFlight)
+	}
+	if len(results) != len(stories) {
+		t.Fatalf("结果数量 = %d, want %d", len(results), len(stories))
+	}
+	seen := make(map[int]bool)
+	for idx, r := range results {
+		if !r.Success {
+			t.Fatalf("行%d 导入失败: %v", idx+1, r.Error)
+		}
+		if r.StoryType != string(stories[idx].Type) {
+			t.Errorf("结果 %d 类型 = %s, want %s（结果应按输入顺序排列）", idx, r.StoryType, stories[idx].Type)
+		}
+		if seen[r.StoryID] {
+			t.Errorf("禅道ID %d 重复", r.StoryID)
+		}
+		seen[r.StoryID] = true
+	}
+	for _, s := range stories[1:] {
+		if parents[s.Title] != 501 {
+			t.Errorf("研发需求 %s 的父需求ID = %d, want 501", s.Title, parents[s.Title])
+		}
+	}
+}
+
+func TestImporter_ImportStories_ConcurrentSameTypeParent(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	var mu sync.Mutex
+	parents := make(map[string]int)
+	mockStorySvc := &mockStoryService{
+		createFn: func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			if req.Title == "父" {
+				time.Sleep(30 * time.Millisecond)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			parents[req.Title] = req.Parent
+			id := 900 + len(parents)
+			return &StoryCreateResponse{Status: "success", ID: id}, nil, nil
+		},
+	}
+
+	importer := NewImporterWithMocks(log, &mockEpicService{}, &mockReqService{}, mockStorySvc, &mockConfig{})
+	importer.SetConcurrency(4)
+
+	// 子需求写在父需求上方：两者层级不同，分属不同阶段，父需求所在阶段全部完成后才创建子需求
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeStory, Title: "子", ProductID: 1, ParentRef: "@2", RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "父", ProductID: 1, RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "其他", ProductID: 1, RowIndex: 3},
+	})
+
+	if !results[1].Success {
+		t.Fatalf("父需求导入失败: %v", results[1].Error)
+	}
+	if parents["子"] != results[1].StoryID {
+		t.Errorf("子需求的父需求ID = %d, want %d（父需求应在更早的阶段导入完成）", parents["子"], results[1].StoryID)
+	}
+}
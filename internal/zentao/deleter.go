@@ -45,6 +45,7 @@ type Deleter struct {
 	epicDeleter  EpicCreator
 	reqDeleter   RequirementCreator
 	storyDeleter StoryCreator
+	upsertMap    *UpsertMap // 回滚时同步删除外部ID映射，为nil时不处理
 }
 
 // NewDeleter 创建新的删除器
@@ -194,53 +195,17 @@ func (d *Deleter) DeleteStoriesConcurrent(ids []TypedID, concurrency int) []Dele
 
 // FetchByFilter 按筛选条件获取需求列表
 // 支持按产品ID（必填）+ 标题部分匹配 + 创建者筛选
-// 去重策略：Epic API会返回关联的Requirement和Story，Requirement API会返回关联的Story
-// 因此先获取Story，再获取Requirement（去除Story中已有的ID），最后获取Epic（去除Story和Requirement中已有的ID）
+// 按类型分层去重（见 listProductItems），避免禅道API返回的重复ID
 func (d *Deleter) FetchByFilter(filter DeleteFilter) []TypedID {
 	var matched []TypedID
-	seenIDs := make(map[int]bool) // 已处理的ID集合，用于去重
 
-	// 第一步：获取Story列表（最小集合，不含其他类型的关联数据）
-	stories, err := d.storyDeleter.ProductsListAll(filter.ProductID)
-	if err != nil {
-		d.logger.Error("获取产品研发需求列表失败: %v", err)
-	} else {
-		for _, s := range stories {
-			seenIDs[s.ID] = true
-			if d.matchFilter(s.Title, s.OpenedBy, filter) {
-				matched = append(matched, TypedID{ID: s.ID, Type: story.StoryTypeStory, Title: s.Title, OpenedBy: s.OpenedBy})
-			}
-		}
+	items, errs := listProductItems(filter.ProductID, d.epicDeleter, d.reqDeleter, d.storyDeleter)
+	for _, err := range errs {
+		d.logger.Error("%v", err)
 	}
-
-	// 第二步：获取Requirement列表，去除已在Story中出现的ID
-	requirements, err := d.reqDeleter.ProductsListAll(filter.ProductID)
-	if err != nil {
-		d.logger.Error("获取产品用户需求列表失败: %v", err)
-	} else {
-		for _, r := range requirements {
-			if seenIDs[r.ID] {
-				continue // 跳过已在Story列表中出现的ID
-			}
-			seenIDs[r.ID] = true
-			if d.matchFilter(r.Title, r.OpenedBy, filter) {
-				matched = append(matched, TypedID{ID: r.ID, Type: story.StoryTypeRequirement, Title: r.Title, OpenedBy: r.OpenedBy})
-			}
-		}
-	}
-
-	// 第三步：获取Epic列表，去除已在Story或Requirement中出现的ID
-	epics, err := d.epicDeleter.ProductsListAll(filter.ProductID)
-	if err != nil {
-		d.logger.Error("获取产品业务需求列表失败: %v", err)
-	} else {
-		for _, e := range epics {
-			if seenIDs[e.ID] {
-				continue // 跳过已在Story或Requirement列表中出现的ID
-			}
-			if d.matchFilter(e.Title, e.OpenedBy, filter) {
-				matched = append(matched, TypedID{ID: e.ID, Type: story.StoryTypeEpic, Title: e.Title, OpenedBy: e.OpenedBy})
-			}
+	for _, item := range items {
+		if d.matchFilter(item.Title, item.OpenedBy, filter) {
+			matched = append(matched, TypedID{ID: item.ID, Type: item.Type, Title: item.Title, OpenedBy: item.OpenedBy})
 		}
 	}
 
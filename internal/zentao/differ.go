@@ -0,0 +1,265 @@
+// Package zentao 封装禅道API客户端 - Excel与禅道产品的差异比对
+package zentao
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// 差异类型
+const (
+	DiffAdded    = "added"    // Excel中有，禅道中没有
+	DiffRemoved  = "removed"  // 禅道中有，Excel中没有
+	DiffModified = "modified" // 两边都有但字段不同
+)
+
+// FieldChange 字段级差异
+type FieldChange struct {
+	Field  string // 字段名
+	Zentao string // 禅道中的值
+	Excel  string // Excel中的值
+}
+
+// DiffEntry 一条差异记录
+type DiffEntry struct {
+	Kind      string
+	Type      story.StoryType
+	Title     string
+	ProductID int
+	RowIndex  int // Excel行号（removed时为0）
+	ZentaoID  int // 禅道ID（added时为0）
+	Changes   []FieldChange
+}
+
+// DiffResult 单个产品的比对结果
+type DiffResult struct {
+	Entries   []DiffEntry
+	Unchanged int // 两边一致的需求数量
+}
+
+// Differ 比对Excel与禅道产品当前的需求
+type Differ struct {
+	logger      *logger.Logger
+	epicLister  EpicCreator
+	reqLister   RequirementCreator
+	storyLister StoryCreator
+	config      ConfigProvider
+}
+
+// NewDiffer 创建新的比对器
+func NewDiffer(client *Client, log *logger.Logger) *Differ {
+	return NewDifferWithMocks(log, client.Epic, client.Requirement, client.Story, client.config)
+}
+
+// NewDifferWithMocks 创建比对器（用于测试）
+func NewDifferWithMocks(log *logger.Logger, epic EpicCreator, req RequirementCreator, story StoryCreator, cfg ConfigProvider) *Differ {
+	return &Differ{
+		logger:      log,
+		epicLister:  epic,
+		reqLister:   req,
+		storyLister: story,
+		config:      cfg,
+	}
+}
+
+// Diff 比对产品在禅道中的需求与Excel中属于该产品的行
+// 匹配规则：Excel行填写了禅道ID时按ID匹配，否则按 类型+标题+父需求路径 匹配
+func (d *Differ) Diff(productID int, stories []story.Story) (*DiffResult, error) {
+	items, errs := listProductItems(productID, d.epicLister, d.reqLister, d.storyLister)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			d.logger.Error("%v", err)
+		}
+		return nil, fmt.Errorf("获取产品 %d 的需求列表失败: %w", productID, errs[0])
+	}
+
+	itemByID := make(map[int]ProductItem, len(items))
+	for _, item := range items {
+		itemByID[item.ID] = item
+	}
+	var rows []story.Story
+	rowByIndex := make(map[int]story.Story)
+	for _, s := range stories {
+		if s.ProductID == productID {
+			rows = append(rows, s)
+			rowByIndex[s.RowIndex] = s
+		}
+	}
+
+	// 禅道侧的匹配键 -> 尚未匹配的需求ID（同键多条时按顺序依次匹配）
+	itemKeys := make(map[string][]int)
+	for _, item := range items {
+		key := itemMatchKey(item, itemByID, 0)
+		itemKeys[key] = append(itemKeys[key], item.ID)
+	}
+
+	result := &DiffResult{}
+	matched := make(map[int]bool)
+	for _, s := range rows {
+		var item ProductItem
+		found := false
+		if s.ZentaoID > 0 {
+			item, found = itemByID[s.ZentaoID]
+		} else {
+			key := rowMatchKey(s, rowByIndex, itemByID, 0)
+			for _, id := range itemKeys[key] {
+				if !matched[id] {
+					item, found = itemByID[id], true
+					break
+				}
+			}
+		}
+
+		if !found || matched[item.ID] {
+			result.Entries = append(result.Entries, DiffEntry{
+				Kind: DiffAdded, Type: s.Type, Title: s.Title, ProductID: productID, RowIndex: s.RowIndex,
+			})
+			continue
+		}
+		matched[item.ID] = true
+
+		changes := d.compareFields(s, item)
+		if s.ZentaoID > 0 && s.Title != item.Title {
+			// 按禅道ID匹配时标题也可能被修改
+			changes = append([]FieldChange{{Field: "标题", Zentao: item.Title, Excel: s.Title}}, changes...)
+		}
+		if len(changes) > 0 {
+			result.Entries = append(result.Entries, DiffEntry{
+				Kind: DiffModified, Type: s.Type, Title: s.Title, ProductID: productID,
+				RowIndex: s.RowIndex, ZentaoID: item.ID, Changes: changes,
+			})
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	for _, item := range items {
+		if !matched[item.ID] {
+			result.Entries = append(result.Entries, DiffEntry{
+				Kind: DiffRemoved, Type: item.Type, Title: item.Title, ProductID: productID, ZentaoID: item.ID,
+			})
+		}
+	}
+
+	d.logger.Info("产品 %d 比对完成: 新增 %d，删除 %d，修改 %d，一致 %d", productID,
+		countDiff(result.Entries, DiffAdded), countDiff(result.Entries, DiffRemoved),
+		countDiff(result.Entries, DiffModified), result.Unchanged)
+	return result, nil
+}
+
+// compareFields 比对需求字段（优先级、分类、描述、验收标准、预计工时、模块）
+func (d *Differ) compareFields(s story.Story, item ProductItem) []FieldChange {
+	// 与导入时相同的模块回退规则：Excel未填写(-1)时使用配置默认值
+	module := s.Module
+	if module < 0 {
+		module = d.config.GetDefaultModule()
+		if module < 0 {
+			module = 0
+		}
+	}
+
+	var changes []FieldChange
+	add := func(field, zentaoValue, excelValue string) {
+		if strings.TrimSpace(zentaoValue) != strings.TrimSpace(excelValue) {
+			changes = append(changes, FieldChange{Field: field, Zentao: zentaoValue, Excel: excelValue})
+		}
+	}
+	add("优先级", strconv.Itoa(item.Pri), strconv.Itoa(s.Priority))
+	add("分类", item.Category, s.Category)
+	add("需求描述", item.Spec, s.Spec)
+	add("验收标准", item.Verify, s.Verify)
+	add("预计工时", strconv.FormatFloat(item.Estimate, 'f', -1, 64), strconv.FormatFloat(s.Estimate, 'f', -1, 64))
+	add("模块ID", strconv.Itoa(item.Module), strconv.Itoa(module))
+	return changes
+}
+
+// maxMatchDepth 计算父需求路径时的最大深度（防止父子关系成环导致无限递归）
+const maxMatchDepth = 16
+
+// itemMatchKey 计算禅道需求的匹配键：类型|标题，父需求路径以 " / " 连接在前
+func itemMatchKey(item ProductItem, itemByID map[int]ProductItem, depth int) string {
+	key := string(item.Type) + "|" + item.Title
+	if parent, ok := itemByID[item.Parent]; ok && item.Parent > 0 && depth < maxMatchDepth {
+		return itemMatchKey(parent, itemByID, depth+1) + " / " + key
+	}
+	return key
+}
+
+// rowMatchKey 计算Excel行的匹配键，父需求可以是 "@行号" 引用或禅道ID
+func rowMatchKey(s story.Story, rowByIndex map[int]story.Story, itemByID map[int]ProductItem, depth int) string {
+	key := string(s.Type) + "|" + s.Title
+	if depth >= maxMatchDepth {
+		return key
+	}
+	if strings.HasPrefix(s.ParentRef, "@") {
+		if rowNum, err := strconv.Atoi(strings.TrimPrefix(s.ParentRef, "@")); err == nil {
+			if parent, ok := rowByIndex[rowNum]; ok {
+				return rowMatchKey(parent, rowByIndex, itemByID, depth+1) + " / " + key
+			}
+		}
+	} else if parent, ok := itemByID[s.ParentID]; ok && s.ParentID > 0 {
+		return itemMatchKey(parent, itemByID, depth+1) + " / " + key
+	}
+	return key
+}
+
+// countDiff 统计指定类型的差异数量
+func countDiff(entries []DiffEntry, kind string) int {
+	count := 0
+	for _, e := range entries {
+		if e.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// FormatDiff 格式化比对结果用于显示
+func FormatDiff(entries []DiffEntry, unchanged int) string {
+	var b strings.Builder
+
+	sections := []struct {
+		kind  string
+		title string
+		mark  string
+	}{
+		{DiffAdded, "新增（Excel中有，禅道中没有）", "+"},
+		{DiffRemoved, "删除（禅道中有，Excel中没有）", "-"},
+		{DiffModified, "修改", "~"},
+	}
+	for _, sec := range sections {
+		count := countDiff(entries, sec.kind)
+		b.WriteString(fmt.Sprintf("%s: %d 条\n", sec.title, count))
+		for _, e := range entries {
+			if e.Kind != sec.kind {
+				continue
+			}
+			location := fmt.Sprintf("行%d", e.RowIndex)
+			if e.RowIndex == 0 {
+				location = fmt.Sprintf("ID %d", e.ZentaoID)
+			} else if e.ZentaoID > 0 {
+				location = fmt.Sprintf("行%d ↔ ID %d", e.RowIndex, e.ZentaoID)
+			}
+			b.WriteString(fmt.Sprintf("  %s [%s] %s (产品%d, %s)\n", sec.mark, getTypeDisplayName(e.Type), e.Title, e.ProductID, location))
+			for _, c := range e.Changes {
+				b.WriteString(fmt.Sprintf("      %s: %q → %q\n", c.Field, truncateText(c.Zentao, 60), truncateText(c.Excel, 60)))
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(fmt.Sprintf("一致: %d 条\n", unchanged))
+	return b.String()
+}
+
+// truncateText 按字符截断文本，避免长描述撑满屏幕
+func truncateText(s string, maxRunes int) string {
+	runes := []rune(s)
+	if len(runes) > maxRunes {
+		return string(runes[:maxRunes]) + "..."
+	}
+	return s
+}
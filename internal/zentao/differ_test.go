@@ -0,0 +1,91 @@
+package zentao
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestDiffer_Diff(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	mockEpic := &mockEpicService{
+		listFn: func(productID int) ([]EpicListItem, error) {
+			return []EpicListItem{{ID: 10, Title: "E", Pri: 1, Module: 5}}, nil
+		},
+	}
+	mockReq := &mockReqService{
+		listFn: func(productID int) ([]RequirementListItem, error) {
+			return []RequirementListItem{
+				{ID: 20, Title: "R", Parent: float64(10), Pri: 2, Spec: "旧描述", Module: 5},
+				{ID: 21, Title: "只在禅道", Parent: float64(10), Pri: 3},
+			}, nil
+		},
+	}
+	mockStorySvc := &mockStoryService{
+		listFn: func(productID int) ([]StoryListItem, error) {
+			return []StoryListItem{
+				{ID: 30, Title: "S", Parent: float64(20), Pri: 3, Module: 5},
+				{ID: 40, Title: "改过标题", Pri: 3, Module: 5},
+			}, nil
+		},
+	}
+
+	stories := []story.Story{
+		{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, Priority: 1, Module: -1, RowIndex: 1},
+		{Type: story.StoryTypeRequirement, Title: "R", ProductID: 1, Priority: 2, Spec: "新描述", ParentRef: "@1", Module: -1, RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "S", ProductID: 1, Priority: 3, ParentRef: "@2", Module: -1, RowIndex: 3},
+		{Type: story.StoryTypeStory, Title: "只在Excel", ProductID: 1, Priority: 3, Module: -1, RowIndex: 4},
+		{Type: story.StoryTypeStory, Title: "新标题", ProductID: 1, Priority: 3, ZentaoID: 40, Module: -1, RowIndex: 5},
+		{Type: story.StoryTypeStory, Title: "其他产品", ProductID: 2, Priority: 3, RowIndex: 6},
+	}
+
+	differ := NewDifferWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{module: 5})
+	result, err := differ.Diff(1, stories)
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+
+	if result.Unchanged != 2 {
+		t.Errorf("一致数量应为2（E和S）, 得到 %d: %+v", result.Unchanged, result.Entries)
+	}
+
+	byTitle := make(map[string]DiffEntry)
+	for _, e := range result.Entries {
+		byTitle[e.Title] = e
+	}
+	if e := byTitle["只在Excel"]; e.Kind != DiffAdded || e.RowIndex != 4 {
+		t.Errorf("只在Excel 应为新增, 得到 %+v", e)
+	}
+	if e := byTitle["只在禅道"]; e.Kind != DiffRemoved || e.ZentaoID != 21 {
+		t.Errorf("只在禅道 应为删除, 得到 %+v", e)
+	}
+	e := byTitle["R"]
+	if e.Kind != DiffModified || len(e.Changes) != 1 || e.Changes[0].Field != "需求描述" {
+		t.Fatalf("R 应只有需求描述被修改, 得到 %+v", e)
+	}
+	if e.Changes[0].Zentao != "旧描述" || e.Changes[0].Excel != "新描述" {
+		t.Errorf("需求描述差异 = %+v", e.Changes[0])
+	}
+	// 填写了禅道ID的行按ID匹配，标题不同视为修改而不是新增
+	if e := byTitle["新标题"]; e.Kind != DiffModified || e.ZentaoID != 40 || len(e.Changes) != 1 || e.Changes[0].Field != "标题" {
+		t.Errorf("按禅道ID匹配的行应只有标题被修改, 得到 %+v", e)
+	}
+	if _, ok := byTitle["其他产品"]; ok {
+		t.Error("其他产品的行不应参与比对")
+	}
+	if len(result.Entries) != 4 {
+		t.Errorf("期望4条差异, 得到 %d: %+v", len(result.Entries), result.Entries)
+	}
+
+	out := FormatDiff(result.Entries, result.Unchanged)
+	for _, want := range []string{"新增（Excel中有，禅道中没有）: 1 条", "删除（禅道中有，Excel中没有）: 1 条", "修改: 2 条", "一致: 2 条"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("FormatDiff() 缺少 %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+// Package zentao 封装禅道API客户端 - 导入演练（dry-run）
+package zentao
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// PlannedRequest 表示演练模式下某一行将要发送的创建请求
+type PlannedRequest struct {
+	RowIndex  int
+	StoryType story.StoryType
+	Title     string
+	ParentRef string      // 父需求引用（"@n" 在演练模式下无法解析为实际ID，"@引用键" 显示为 "@引用键（行n）"）
+	Payload   interface{} // EpicCreateRequest / RequirementCreateRequest / StoryCreateRequest
+}
+
+// PlanStories 按导入顺序构建每行需求的创建请求，但不调用禅道创建API
+// 层级(grade)按 "@行号" 父需求链计算，父需求为禅道ID时暂按第1级显示
+// 传入的stories不会被修改
+func (i *Importer) PlanStories(stories []story.Story) []PlannedRequest {
+	stories = append([]story.Story(nil), stories...)
+	story.ResolveKeyRefs(stories)
+	story.AssignGrades(stories)
+
+	plans := make([]PlannedRequest, 0, len(stories))
+	for _, wave := range importWaves(stories) {
+		for _, idx := range wave {
+			plans = append(plans, i.planStory(stories[idx]))
+		}
+	}
+	return plans
+}
+
+// planStory 构建单行需求的创建请求
+func (i *Importer) planStory(s story.Story) PlannedRequest {
+	plan := PlannedRequest{
+		RowIndex:  s.RowIndex,
+		StoryType: s.Type,
+		Title:     s.Title,
+		ParentRef: parentLabel(&s),
+	}
+	switch s.Type {
+	case story.StoryTypeEpic:
+		plan.Payload = i.buildEpicRequest(&s)
+	case story.StoryTypeRequirement:
+		plan.Payload = i.buildRequirementRequest(&s)
+	default:
+		plan.Payload = i.buildStoryRequest(&s)
+	}
+	return plan
+}
+
+// FormatPlan 格式化演练计划用于显示，包含每行将发送的完整请求体
+func FormatPlan(plans []PlannedRequest) string {
+	var b strings.Builder
+	for n, plan := range plans {
+		s := story.Story{Type: plan.StoryType}
+		b.WriteString(fmt.Sprintf("[%d/%d] 行%d %s: %s\n", n+1, len(plans), plan.RowIndex, s.GetTypeString(), plan.Title))
+		if strings.HasPrefix(plan.ParentRef, "@") {
+			b.WriteString(fmt.Sprintf("    父需求: %s（导入时解析为该行创建后的禅道ID，请求体中parent暂为0）\n", plan.ParentRef))
+		}
+		payload, err := json.MarshalIndent(plan.Payload, "    ", "  ")
+		if err != nil {
+			b.WriteString(fmt.Sprintf("    请求体序列化失败: %v\n", err))
+			continue
+		}
+		b.WriteString("    请求体: ")
+		b.Write(payload)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -115,6 +115,7 @@ type EpicListItem struct {
 	Status      string      `json:"status"`
 	Stage       string      `json:"stage"`
 	OpenedBy    string      `json:"openedBy"`
+	Grade       int         `json:"grade"`
 	OpenedDate  string      `json:"openedDate"`
 	AssignedTo  string      `json:"assignedTo"`
 	Spec        string      `json:"spec"`
@@ -0,0 +1,177 @@
+// Package zentao 封装禅道API客户端 - 需求树导出
+package zentao
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// Exporter 从禅道导出产品的需求树
+type Exporter struct {
+	logger      *logger.Logger
+	epicLister  EpicCreator
+	reqLister   RequirementCreator
+	storyLister StoryCreator
+	modules     ModuleLister
+}
+
+// NewExporter 创建新的导出器
+func NewExporter(client *Client, log *logger.Logger) *Exporter {
+	return &Exporter{
+		logger:      log,
+		epicLister:  client.Epic,
+		reqLister:   client.Requirement,
+		storyLister: client.Story,
+		modules:     client.Module,
+	}
+}
+
+// NewExporterWithMocks 创建导出器（用于测试）
+func NewExporterWithMocks(log *logger.Logger, epic EpicCreator, req RequirementCreator, story StoryCreator, modules ModuleLister) *Exporter {
+	return &Exporter{
+		logger:      log,
+		epicLister:  epic,
+		reqLister:   req,
+		storyLister: story,
+		modules:     modules,
+	}
+}
+
+// Export 获取产品的全部需求并重建父子关系，返回可直接写入导入模板的需求列表
+// 行顺序保证父需求在子需求之前，RowIndex 从1开始连续编号
+// 父需求在导出范围内时以 "@行号" 引用，否则保留其禅道ID
+// 模块ID只在本产品内有效，导出为模块路径（如 "/前端/支付"），导入其他产品时按路径重新解析
+func (e *Exporter) Export(productID int) ([]story.Story, error) {
+	items, errs := listProductItems(productID, e.epicLister, e.reqLister, e.storyLister)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			e.logger.Error("%v", err)
+		}
+		return nil, fmt.Errorf("获取产品 %d 的需求列表失败: %w", productID, errs[0])
+	}
+
+	modulePaths, err := e.modulePaths(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := orderByHierarchy(items)
+
+	rowOf := make(map[int]int, len(ordered)) // 禅道ID -> 导出行号
+	for idx, item := range ordered {
+		rowOf[item.ID] = idx + 1
+	}
+
+	stories := make([]story.Story, len(ordered))
+	for idx, item := range ordered {
+		s := story.Story{
+			Type:       item.Type,
+			Title:      item.Title,
+			ProductID:  productID,
+			Priority:   item.Pri,
+			Category:   item.Category,
+			Spec:       item.Spec,
+			Source:     item.Source,
+			SourceNote: item.SourceNote,
+			Estimate:   item.Estimate,
+			Keywords:   item.Keywords,
+			Verify:     item.Verify,
+			Module:     item.Module,
+			RowIndex:   idx + 1,
+		}
+		if item.Module > 0 {
+			if path, ok := modulePaths[item.Module]; ok {
+				s.ModulePath = path
+			} else {
+				s.Module = -1
+				e.logger.Info("需求 %d 的模块 %d 不在产品 %d 的模块树中，模块列留空", item.ID, item.Module, productID)
+			}
+		}
+		if item.Parent > 0 {
+			if row, ok := rowOf[item.Parent]; ok {
+				s.ParentRef = "@" + strconv.Itoa(row)
+			} else {
+				s.ParentRef = strconv.Itoa(item.Parent)
+				s.ParentID = item.Parent
+				e.logger.Info("需求 %d 的父需求 %d 不在导出范围内，保留禅道ID", item.ID, item.Parent)
+			}
+		}
+		stories[idx] = s
+	}
+
+	e.logger.Info("产品 %d 共导出 %d 个需求", productID, len(stories))
+	return stories, nil
+}
+
+// modulePaths 返回产品的模块ID -> 以 "/" 开头的模块路径（开头的 "/" 使纯数字的模块名不会被当作模块ID读取）
+// 路径重复的模块只保留第一个，与导入时 ModuleResolver 的解析结果一致
+func (e *Exporter) modulePaths(productID int) (map[int]string, error) {
+	modules, err := e.modules.ListByProduct(productID)
+	if err != nil {
+		return nil, fmt.Errorf("获取产品 %d 的模块列表失败: %w", productID, err)
+	}
+	byID := make(map[int]string, len(modules))
+	for path, id := range buildModulePaths(modules) {
+		byID[id] = "/" + path
+	}
+	return byID, nil
+}
+
+// orderByHierarchy 按父子关系深度优先排序：先按类型层级(Epic→Requirement→Story)和ID排列根节点，再依次展开子节点
+// 父需求不在列表中的需求视为根节点
+func orderByHierarchy(items []ProductItem) []ProductItem {
+	byID := make(map[int]bool, len(items))
+	for _, item := range items {
+		byID[item.ID] = true
+	}
+
+	children := make(map[int][]ProductItem)
+	var roots []ProductItem
+	for _, item := range items {
+		if item.Parent > 0 && byID[item.Parent] && item.Parent != item.ID {
+			children[item.Parent] = append(children[item.Parent], item)
+		} else {
+			roots = append(roots, item)
+		}
+	}
+
+	less := func(list []ProductItem) func(a, b int) bool {
+		return func(a, b int) bool {
+			la, lb := list[a].Type.Level(), list[b].Type.Level()
+			if la != lb {
+				return la < lb
+			}
+			return list[a].ID < list[b].ID
+		}
+	}
+
+	ordered := make([]ProductItem, 0, len(items))
+	visited := make(map[int]bool, len(items))
+	var visit func(item ProductItem)
+	visit = func(item ProductItem) {
+		if visited[item.ID] {
+			return
+		}
+		visited[item.ID] = true
+		ordered = append(ordered, item)
+		kids := children[item.ID]
+		sort.SliceStable(kids, less(kids))
+		for _, kid := range kids {
+			visit(kid)
+		}
+	}
+
+	sort.SliceStable(roots, less(roots))
+	for _, root := range roots {
+		visit(root)
+	}
+	// 父子关系成环时环上的需求不会从根节点访问到，按原顺序追加
+	for _, item := range items {
+		visit(item)
+	}
+	return ordered
+}
@@ -0,0 +1,89 @@
+package zentao
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestExporter_Export(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	// Epic/Requirement API会返回关联的下层需求，导出时需分层去重
+	mockEpic := &mockEpicService{
+		listFn: func(productID int) ([]EpicListItem, error) {
+			return []EpicListItem{
+				{ID: 10, Title: "E", Pri: 1, Category: "feature", Module: 5},
+				{ID: 20, Title: "R", Parent: float64(10)},
+				{ID: 30, Title: "S", Parent: "20"},
+			}, nil
+		},
+	}
+	mockReq := &mockReqService{
+		listFn: func(productID int) ([]RequirementListItem, error) {
+			return []RequirementListItem{
+				{ID: 20, Title: "R", Parent: float64(10), Estimate: "2.5", Module: 42},
+				{ID: 30, Title: "S", Parent: "20"},
+			}, nil
+		},
+	}
+	mockStorySvc := &mockStoryService{
+		listFn: func(productID int) ([]StoryListItem, error) {
+			return []StoryListItem{
+				{ID: 31, Title: "S2", Parent: float64(999)}, // 父需求不在本产品中
+				{ID: 30, Title: "S", Parent: "20"},
+			}, nil
+		},
+	}
+
+	mockModules := &mockModuleService{
+		listFn: func(productID int) ([]Module, error) {
+			return []Module{{ID: 3, Name: "前端"}, {ID: 5, Name: "支付", Parent: 3}}, nil
+		},
+	}
+
+	exporter := NewExporterWithMocks(log, mockEpic, mockReq, mockStorySvc, mockModules)
+	stories, err := exporter.Export(78)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	want := []struct {
+		typ       story.StoryType
+		title     string
+		parentRef string
+	}{
+		{story.StoryTypeEpic, "E", ""},
+		{story.StoryTypeRequirement, "R", "@1"},
+		{story.StoryTypeStory, "S", "@2"},
+		{story.StoryTypeStory, "S2", "999"},
+	}
+	if len(stories) != len(want) {
+		t.Fatalf("期望导出%d条, 得到 %d: %+v", len(want), len(stories), stories)
+	}
+	for idx, w := range want {
+		s := stories[idx]
+		if s.Type != w.typ || s.Title != w.title || s.ParentRef != w.parentRef || s.RowIndex != idx+1 {
+			t.Errorf("第%d行 = [%s] %s 父:%s 行号:%d, want [%s] %s 父:%s", idx+1, s.Type, s.Title, s.ParentRef, s.RowIndex, w.typ, w.title, w.parentRef)
+		}
+		if s.ProductID != 78 {
+			t.Errorf("第%d行产品ID应为78, 得到 %d", idx+1, s.ProductID)
+		}
+	}
+	// 模块ID只在本产品有效，导出为路径；不在模块树中的模块留空，未归属模块保留0
+	if stories[0].ModulePath != "/前端/支付" {
+		t.Errorf("模块路径应为 /前端/支付, 得到 %q", stories[0].ModulePath)
+	}
+	if stories[1].ModulePath != "" || stories[1].Module != -1 {
+		t.Errorf("未知模块应留空, 得到 路径%q ID%d", stories[1].ModulePath, stories[1].Module)
+	}
+	if stories[2].ModulePath != "" || stories[2].Module != 0 {
+		t.Errorf("未归属模块应保留0, 得到 路径%q ID%d", stories[2].ModulePath, stories[2].Module)
+	}
+	if stories[1].Estimate != 2.5 {
+		t.Errorf("预计工时应为2.5, 得到 %v", stories[1].Estimate)
+	}
+}
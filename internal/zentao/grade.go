@@ -0,0 +1,102 @@
+// Package zentao 封装禅道API客户端 - 需求层级(grade)与拓扑导入顺序
+package zentao
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// parentItemCache 按产品缓存需求列表，用于查询禅道ID父需求的类型和层级
+type parentItemCache struct {
+	mu    sync.Mutex
+	items map[int]map[int]ProductItem // 产品ID -> 需求ID -> 需求
+}
+
+// newParentItemCache 创建父需求缓存
+func newParentItemCache() *parentItemCache {
+	return &parentItemCache{items: make(map[int]map[int]ProductItem)}
+}
+
+// importWaves 按拓扑顺序将需求分批：先按类型层级(Epic → Requirement → Story)，同类型内再按层级(Grade)由低到高
+// 父需求的类型层级不低于子需求（见 story.ValidateReferences），同类型子需求的层级比父需求大1，
+// 因此每批需求的 "@行号" 父需求都在之前的批次中导入；每批只包含同一类型，返回各批在stories切片中的下标，批内保持原始顺序
+func importWaves(stories []story.Story) [][]int {
+	type waveKey struct{ level, grade int }
+	groups := make(map[waveKey][]int)
+	var keys []waveKey
+	for idx, s := range stories {
+		key := waveKey{s.Type.Level(), storyGrade(&s)}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], idx)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		if keys[a].level != keys[b].level {
+			return keys[a].level < keys[b].level
+		}
+		return keys[a].grade < keys[b].grade
+	})
+
+	waves := make([][]int, 0, len(keys))
+	for _, key := range keys {
+		waves = append(waves, groups[key])
+	}
+	return waves
+}
+
+// storyGrade 返回创建请求中的层级（尚未计算时为1）
+func storyGrade(s *story.Story) int {
+	if s.Grade < 1 {
+		return 1
+	}
+	return s.Grade
+}
+
+// resolveGrade 在父需求解析后确定需求的实际层级，并校验不超过产品允许的最大层级
+// "@行号" 父需求为同类型时取其导入时的层级+1；父需求为禅道ID时查询产品需求列表，同类型时取其层级+1
+func (i *Importer) resolveGrade(run *importRun, s *story.Story) error {
+	grade := 1
+	if row, isRowRef, err := story.ParseRowRef(s.ParentRef); isRowRef {
+		if idx, ok := run.rowIdx[row]; ok && err == nil && run.stories[idx].Type == s.Type {
+			grade = storyGrade(&run.stories[idx]) + 1
+		}
+	} else if s.ParentID > 0 {
+		if parent, ok := i.lookupParent(s.ProductID, s.ParentID); ok && parent.Type == s.Type {
+			grade = max(parent.Grade, 1) + 1
+		}
+	}
+	if grade != storyGrade(s) {
+		i.logger.Debug("行%d 层级由 %d 修正为 %d（父需求: %s）", s.RowIndex, storyGrade(s), grade, s.ParentRef)
+	}
+	s.Grade = grade
+
+	if maxGrade := i.config.GetMaxGrade(s.ProductID); maxGrade > 0 && grade > maxGrade {
+		return fmt.Errorf("%s层级为 %d，超过产品 %d 允许的最大层级 %d（父需求: %s）", s.GetTypeString(), grade, s.ProductID, maxGrade, s.ParentRef)
+	}
+	return nil
+}
+
+// lookupParent 查询产品下的需求（产品需求列表按产品缓存，查询失败时视为找不到）
+func (i *Importer) lookupParent(productID, id int) (ProductItem, bool) {
+	i.parents.mu.Lock()
+	defer i.parents.mu.Unlock()
+
+	byID, ok := i.parents.items[productID]
+	if !ok {
+		items, errs := listProductItems(productID, i.epicCreator, i.reqCreator, i.storyCreator)
+		for _, err := range errs {
+			i.logger.Info("查询父需求层级失败(产品ID=%d): %v", productID, err)
+		}
+		byID = make(map[int]ProductItem, len(items))
+		for _, item := range items {
+			byID[item.ID] = item
+		}
+		i.parents.items[productID] = byID
+	}
+	item, ok := byID[id]
+	return item, ok
+}
@@ -0,0 +1,76 @@
+package zentao
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestImporter_ImportStories_Grades(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	type call struct {
+		title  string
+		parent int
+		grade  int
+	}
+	var calls []call
+	listCalls := 0
+	mockEpic := &mockEpicService{
+		listFn: func(productID int) ([]EpicListItem, error) { return nil, nil },
+	}
+	mockReq := &mockReqService{
+		listFn: func(productID int) ([]RequirementListItem, error) { return nil, nil },
+	}
+	mockStorySvc := &mockStoryService{
+		createFn: func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			calls = append(calls, call{req.Title, req.Parent, req.Grade})
+			return &StoryCreateResponse{Status: "success", ID: 900 + len(calls)}, nil, nil
+		},
+		listFn: func(productID int) ([]StoryListItem, error) {
+			listCalls++
+			return []StoryListItem{{ID: 50, Title: "已有子需求", Grade: 2}}, nil
+		},
+	}
+
+	importer := NewImporterWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{maxGrade: 3})
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeStory, Title: "孙", ProductID: 1, ParentRef: "@3", RowIndex: 1}, // 父需求在后面的行
+		{Type: story.StoryTypeStory, Title: "挂到已有", ProductID: 1, ParentRef: "50", ParentID: 50, RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "子", ProductID: 1, ParentRef: "@4", RowIndex: 3},
+		{Type: story.StoryTypeStory, Title: "根", ProductID: 1, RowIndex: 4},
+		{Type: story.StoryTypeStory, Title: "超出", ProductID: 1, ParentRef: "@2", RowIndex: 5},
+	})
+
+	want := []call{
+		{"挂到已有", 50, 3}, // 禅道中的父需求为第2级
+		{"根", 0, 1},
+		{"子", 902, 2},
+		{"孙", 903, 3},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("创建调用 = %+v, want %+v", calls, want)
+	}
+	for idx, w := range want {
+		if calls[idx] != w {
+			t.Errorf("第%d次创建 = %+v, want %+v", idx+1, calls[idx], w)
+		}
+	}
+	if listCalls != 1 {
+		t.Errorf("父需求列表应按产品缓存只查询1次, 得到 %d", listCalls)
+	}
+
+	if results[4].Success || results[4].Error == nil || !strings.Contains(results[4].Error.Error(), "超过产品 1 允许的最大层级 3") {
+		t.Errorf("超过最大层级的行应导入失败, 得到 %+v", results[4])
+	}
+	for idx, r := range results[:4] {
+		if !r.Success {
+			t.Errorf("行%d 应导入成功, 得到 %+v", idx+1, r)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package zentao 封装禅道API客户端 - 新建需求的实际ID解析
+package zentao
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// idCandidate 列表中的需求（用于确定新建需求的ID）
+type idCandidate struct {
+	ID         int
+	Type       string
+	Title      string
+	Parent     int
+	OpenedBy   string
+	OpenedDate string
+}
+
+// idResolver 解析Epic/Requirement创建后的实际ID（创建API不返回ID）
+// 首次在某产品下创建前对产品的全部需求ID做快照，之后每次创建只需查询一次对应类型的列表：
+// 列表中既不在快照中、也未被本次运行认领的ID即为新建的需求
+type idResolver struct {
+	create   sync.Mutex // 串行化Epic/Requirement的创建与ID解析
+	mu       sync.Mutex
+	known    map[int]map[int]bool // 产品ID -> 已知ID（快照 + 本次运行已认领的ID）
+	snapshot map[int]bool         // 产品ID -> 快照是否完整（列表查询失败时为false）
+}
+
+// newIDResolver 创建ID解析器
+func newIDResolver() *idResolver {
+	return &idResolver{
+		known:    make(map[int]map[int]bool),
+		snapshot: make(map[int]bool),
+	}
+}
+
+// ensureSnapshot 在产品下首次创建Epic/Requirement前记录产品现有的全部需求ID（每个产品只查询一次）
+func (i *Importer) ensureSnapshot(productID int) {
+	i.ids.mu.Lock()
+	defer i.ids.mu.Unlock()
+
+	if _, ok := i.ids.known[productID]; ok {
+		return
+	}
+	known := make(map[int]bool)
+	complete := true
+
+	if stories, err := i.storyCreator.ProductsListAll(productID); err != nil {
+		i.logger.Info("查询产品研发需求列表失败(产品ID=%d): %v", productID, err)
+		complete = false
+	} else {
+		for _, s := range stories {
+			known[s.ID] = true
+		}
+	}
+	if requirements, err := i.reqCreator.ProductsListAll(productID); err != nil {
+		i.logger.Info("查询产品用户需求列表失败(产品ID=%d): %v", productID, err)
+		complete = false
+	} else {
+		for _, r := range requirements {
+			known[r.ID] = true
+		}
+	}
+	if epics, err := i.epicCreator.ProductsListAll(productID); err != nil {
+		i.logger.Info("查询产品业务需求列表失败(产品ID=%d): %v", productID, err)
+		complete = false
+	} else {
+		for _, e := range epics {
+			known[e.ID] = true
+		}
+	}
+
+	i.ids.known[productID] = known
+	i.ids.snapshot[productID] = complete
+	i.logger.Debug("产品 %d 需求ID快照: %d 个", productID, len(known))
+}
+
+// claimID 将本次运行创建的ID标记为已知，避免被后续创建的需求误认
+func (i *Importer) claimID(productID, id int) {
+	if id <= 0 {
+		return
+	}
+	i.ids.mu.Lock()
+	defer i.ids.mu.Unlock()
+	if i.ids.known[productID] == nil {
+		i.ids.known[productID] = make(map[int]bool)
+	}
+	i.ids.known[productID][id] = true
+}
+
+// resolveCreatedID 获取需求创建后的实际ID
+// Story类型的创建API会返回ID，直接使用；Epic和Requirement的创建API不返回ID，查询一次对应类型的产品列表，
+// 取快照之外的新ID；新ID不唯一（如他人同时创建）时依次按 标题、创建者、父需求 筛选，仍不唯一时取最新创建的一个；
+// 没有新ID（快照不完整或列表未及时更新）时退回到按标题匹配尚未认领的同类型需求；仍无法确定时返回创建返回的ID（通常为0）
+func (i *Importer) resolveCreatedID(s *story.Story, createRespID int) int {
+	if s.Type == story.StoryTypeStory || (s.Type != story.StoryTypeEpic && s.Type != story.StoryTypeRequirement) {
+		i.claimID(s.ProductID, createRespID)
+		return createRespID
+	}
+
+	var candidates []idCandidate
+	switch s.Type {
+	case story.StoryTypeEpic:
+		epics, err := i.epicCreator.ProductsListAll(s.ProductID)
+		if err != nil {
+			i.logger.Info("查询产品业务需求列表失败(产品ID=%d): %v", s.ProductID, err)
+			return createRespID
+		}
+		for _, e := range epics {
+			candidates = append(candidates, idCandidate{ID: e.ID, Type: e.Type, Title: e.Title,
+				Parent: parseParentID(e.Parent), OpenedBy: e.OpenedBy, OpenedDate: e.OpenedDate})
+		}
+	case story.StoryTypeRequirement:
+		requirements, err := i.reqCreator.ProductsListAll(s.ProductID)
+		if err != nil {
+			i.logger.Info("查询产品用户需求列表失败(产品ID=%d): %v", s.ProductID, err)
+			return createRespID
+		}
+		for _, r := range requirements {
+			candidates = append(candidates, idCandidate{ID: r.ID, Type: r.Type, Title: r.Title,
+				Parent: parseParentID(r.Parent), OpenedBy: r.OpenedBy, OpenedDate: r.OpenedDate})
+		}
+	}
+
+	i.ids.mu.Lock()
+	defer i.ids.mu.Unlock()
+
+	known := i.ids.known[s.ProductID]
+	var fresh []idCandidate
+	for _, c := range candidates {
+		if !known[c.ID] && (c.Type == "" || c.Type == string(s.Type)) {
+			fresh = append(fresh, c)
+		}
+	}
+
+	id := 0
+	switch {
+	case len(fresh) == 1:
+		id = fresh[0].ID
+		i.logger.Info("通过ID快照确定%s实际ID: %d (标题: %s)", s.GetTypeString(), id, s.Title)
+	case len(fresh) > 1:
+		id = i.pickCandidate(s, fresh)
+		i.logger.Info("产品中出现 %d 个新ID，按标题/创建者/父需求筛选确定%s实际ID: %d (标题: %s)", len(fresh), s.GetTypeString(), id, s.Title)
+	default:
+		// 快照不完整或列表未包含新建的需求时，退回到按标题匹配；快照中已有或本次运行已认领的ID不能使用，
+		// 否则会误认已有的同名需求，导致子需求挂错父需求、回滚时删除已有需求
+		for _, c := range candidates {
+			if c.Title == s.Title && !known[c.ID] && (c.Type == "" || c.Type == string(s.Type)) {
+				id = c.ID
+				break
+			}
+		}
+		if id > 0 {
+			i.logger.Info("未在产品列表中发现新ID，按标题匹配到%s实际ID: %d (标题: %s，可能不准确)", s.GetTypeString(), id, s.Title)
+		}
+	}
+
+	if id == 0 {
+		id = createRespID // 如果创建API返回了ID（某些版本可能支持），直接使用
+		i.logger.Info("无法获取需求实际ID(标题=%s)，创建返回ID=%d", s.Title, createRespID)
+	}
+	if id > 0 {
+		if known == nil {
+			known = make(map[int]bool)
+			i.ids.known[s.ProductID] = known
+		}
+		known[id] = true
+	}
+	return id
+}
+
+// pickCandidate 在多个新ID中选出最可能是本次创建的需求：依次按标题、创建者、父需求筛选，仍有多个时取最新创建的
+func (i *Importer) pickCandidate(s *story.Story, candidates []idCandidate) int {
+	filters := []func(c idCandidate) bool{
+		func(c idCandidate) bool { return c.Title == s.Title },
+		func(c idCandidate) bool { return c.OpenedBy == i.config.GetUsername() },
+		func(c idCandidate) bool { return c.Parent == s.ParentID },
+	}
+	for _, match := range filters {
+		var matched []idCandidate
+		for _, c := range candidates {
+			if match(c) {
+				matched = append(matched, c)
+			}
+		}
+		// 筛选后为空说明该条件不可用（如列表未返回创建者），保留原候选
+		if len(matched) > 0 {
+			candidates = matched
+		}
+		if len(candidates) == 1 {
+			return candidates[0].ID
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].OpenedDate != candidates[b].OpenedDate {
+			return candidates[a].OpenedDate > candidates[b].OpenedDate
+		}
+		return candidates[a].ID > candidates[b].ID
+	})
+	return candidates[0].ID
+}
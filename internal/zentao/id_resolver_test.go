@@ -0,0 +1,85 @@
+package zentao
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestImporter_ResolveCreatedID_Snapshot(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	// 产品中已存在同名Epic(ID 100)；Epic列表随创建增长
+	epics := []EpicListItem{{ID: 100, Title: "同名", OpenedBy: "me"}}
+	nextID := 101
+	listCalls := 0
+	mockEpic := &mockEpicService{
+		createFn: func(r EpicCreateRequest) (*EpicCreateResponse, *req.Response, error) {
+			if r.Title == "并发" {
+				// 他人同时创建了一个同名需求
+				epics = append(epics, EpicListItem{ID: nextID, Title: "并发", OpenedBy: "other", OpenedDate: "2026-10-17 10:00:01"})
+				nextID++
+			}
+			epics = append(epics, EpicListItem{ID: nextID, Title: r.Title, OpenedBy: "me", OpenedDate: "2026-10-17 10:00:00"})
+			nextID++
+			return &EpicCreateResponse{Status: "success"}, nil, nil
+		},
+		listFn: func(productID int) ([]EpicListItem, error) {
+			listCalls++
+			return append([]EpicListItem(nil), epics...), nil
+		},
+	}
+	mockReq := &mockReqService{listFn: func(productID int) ([]RequirementListItem, error) { return nil, nil }}
+	mockStorySvc := &mockStoryService{listFn: func(productID int) ([]StoryListItem, error) { return nil, nil }}
+
+	importer := NewImporterWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{reviewer: "tester", username: "me"})
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeEpic, Title: "同名", ProductID: 1, RowIndex: 1},
+		{Type: story.StoryTypeEpic, Title: "同名", ProductID: 1, RowIndex: 2},
+		{Type: story.StoryTypeEpic, Title: "并发", ProductID: 1, RowIndex: 3},
+	})
+
+	wantIDs := []int{101, 102, 104}
+	for idx, want := range wantIDs {
+		if results[idx].StoryID != want {
+			t.Errorf("行%d StoryID = %d, want %d", idx+1, results[idx].StoryID, want)
+		}
+	}
+	// 快照1次 + 每次创建1次
+	if listCalls != 1+len(wantIDs) {
+		t.Errorf("Epic列表查询次数 = %d, want %d", listCalls, 1+len(wantIDs))
+	}
+}
+
+func TestImporter_ResolveCreatedID_NoFreshID(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	// 列表未及时包含新建的需求：只有已存在的同名Epic(ID 100)和同名的Requirement(ID 200)
+	mockEpic := &mockEpicService{
+		createFn: func(r EpicCreateRequest) (*EpicCreateResponse, *req.Response, error) {
+			return &EpicCreateResponse{Status: "success"}, nil, nil
+		},
+		listFn: func(productID int) ([]EpicListItem, error) {
+			return []EpicListItem{{ID: 100, Title: "同名"}, {ID: 200, Type: "requirement", Title: "同名"}}, nil
+		},
+	}
+	mockReq := &mockReqService{listFn: func(productID int) ([]RequirementListItem, error) { return nil, nil }}
+	mockStorySvc := &mockStoryService{listFn: func(productID int) ([]StoryListItem, error) { return nil, nil }}
+
+	importer := NewImporterWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{reviewer: "tester", username: "me"})
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeEpic, Title: "同名", ProductID: 1, RowIndex: 1},
+	})
+
+	if results[0].Success || results[0].StoryID != 0 {
+		t.Errorf("Success/StoryID = %v/%d, want false/0（不能误认已有的需求）", results[0].Success, results[0].StoryID)
+	}
+	if results[0].Error == nil {
+		t.Error("无法确定实际ID时期望返回错误")
+	}
+}
@@ -3,7 +3,6 @@ package zentao
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -21,6 +20,10 @@ type ImportResult struct {
 	ElapsedTime time.Duration
 	RequestData string // 请求数据（用于调试）
 	ResponseMsg string // 响应消息
+	Resumed     bool   // 是否为续传时从检查点日志恢复（未重新创建）
+	Action      string // 成功时执行的动作：created/updated/unchanged
+	Skipped     bool   // 是否因父需求导入失败或导入中止而跳过（未调用API）
+	SkipReason  string // 跳过原因
 }
 
 // Importer 处理需求导入到禅道
@@ -31,6 +34,13 @@ type Importer struct {
 	reqCreator   RequirementCreator
 	storyCreator StoryCreator
 	config       ConfigProvider
+	journal      *Journal            // 检查点日志（可选）
+	upsertMap    *UpsertMap          // 外部ID映射（可选，设置后启用幂等导入）
+	parentPolicy ParentFailurePolicy // 父需求导入失败时的处理策略
+	ids          *idResolver         // 新建需求的实际ID解析
+	parents      *parentItemCache    // 禅道ID父需求的类型和层级查询缓存
+	concurrency  int                 // 同一层级内的并发导入数（<=1 表示顺序导入）
+	users        *UserIndex          // 用户索引（可选，设置后将评审人/指派给中的姓名解析为账号）
 }
 
 // NewImporter 创建新的导入器
@@ -42,6 +52,8 @@ func NewImporter(client *Client, log *logger.Logger) *Importer {
 		reqCreator:   client.Requirement,
 		storyCreator: client.Story,
 		config:       client.config,
+		ids:          newIDResolver(),
+		parents:      newParentItemCache(),
 	}
 }
 
@@ -53,9 +65,21 @@ func NewImporterWithMocks(log *logger.Logger, epic EpicCreator, req RequirementC
 		reqCreator:   req,
 		storyCreator: story,
 		config:       cfg,
+		ids:          newIDResolver(),
+		parents:      newParentItemCache(),
 	}
 }
 
+// SetJournal 设置检查点日志：每个需求导入完成后写入日志，日志中已创建的行在导入时跳过并复用其ID
+func (i *Importer) SetJournal(j *Journal) {
+	i.journal = j
+}
+
+// SetUserIndex 设置用户索引：Excel和配置中的评审人、指派给可以填写姓名，创建请求中解析为账号
+func (i *Importer) SetUserIndex(idx *UserIndex) {
+	i.users = idx
+}
+
 // ImportStory 导入单个需求
 func (i *Importer) ImportStory(s *story.Story) ImportResult {
 	start := time.Now()
@@ -97,6 +121,7 @@ func (i *Importer) ImportStory(s *story.Story) ImportResult {
 		i.logger.Success("%s创建成功，ID: %d", s.GetTypeString(), createdID)
 		result.StoryID = createdID
 		result.Success = true
+		result.Action = ImportActionCreated
 	}
 
 	result.ElapsedTime = time.Since(start)
@@ -119,13 +144,13 @@ func (i *Importer) getResponseBody(rsp *req.Response) string {
 	return rsp.String()
 }
 
-// createEpic 创建业务需求
-func (i *Importer) createEpic(s *story.Story) (int, *req.Response, error) {
+// buildEpicRequest 构建业务需求创建请求
+func (i *Importer) buildEpicRequest(s *story.Story) EpicCreateRequest {
 	req := EpicCreateRequest{
 		ProductID:  s.ProductID,
 		Title:      s.Title,
 		Pri:        s.Priority,
-		Grade:      1,
+		Grade:      storyGrade(s),
 		Spec:       s.Spec,
 		Category:   s.Category,
 		Parent:     s.ParentID,
@@ -136,13 +161,18 @@ func (i *Importer) createEpic(s *story.Story) (int, *req.Response, error) {
 		Estimate:   s.Estimate,
 	}
 
-	// 设置评审人（如果配置了默认评审人）
-	if i.config.GetDefaultReviewer() != "" {
-		req.Reviewer = []string{i.config.GetDefaultReviewer()}
-	}
+	// 设置评审人和指派给（Excel未填写时使用配置默认值）
+	req.Reviewer = i.resolveReviewers(s)
+	req.AssignedTo = i.resolveAssignedTo(s)
 
 	// 设置模块ID（优先使用Excel中指定的模块ID，否则使用配置文件默认值）
 	req.Module = i.resolveModule(s.Module)
+	return req
+}
+
+// createEpic 创建业务需求
+func (i *Importer) createEpic(s *story.Story) (int, *req.Response, error) {
+	req := i.buildEpicRequest(s)
 
 	i.logger.Debug("创建业务请求 - 产品ID: %d, 标题: %s, 模块ID: %d", s.ProductID, s.Title, req.Module)
 
@@ -158,13 +188,13 @@ func (i *Importer) createEpic(s *story.Story) (int, *req.Response, error) {
 	return resp.ID, rsp, nil
 }
 
-// createRequirement 创建用户需求
-func (i *Importer) createRequirement(s *story.Story) (int, *req.Response, error) {
+// buildRequirementRequest 构建用户需求创建请求
+func (i *Importer) buildRequirementRequest(s *story.Story) RequirementCreateRequest {
 	req := RequirementCreateRequest{
 		ProductID:  s.ProductID,
 		Title:      s.Title,
 		Pri:        s.Priority,
-		Grade:      1,
+		Grade:      storyGrade(s),
 		Spec:       s.Spec,
 		Category:   s.Category,
 		Parent:     s.ParentID,
@@ -173,15 +203,21 @@ func (i *Importer) createRequirement(s *story.Story) (int, *req.Response, error)
 		Keywords:   s.Keywords,
 		Verify:     s.Verify,
 		Estimate:   s.Estimate,
+		Plan:       s.Plan,
 	}
 
 	// 设置模块ID（优先使用Excel中指定的模块ID，否则使用配置文件默认值）
 	req.Module = i.resolveModule(s.Module)
 
-	// 设置评审人（如果配置了默认评审人）
-	if i.config.GetDefaultReviewer() != "" {
-		req.Reviewer = []string{i.config.GetDefaultReviewer()}
-	}
+	// 设置评审人和指派给（Excel未填写时使用配置默认值）
+	req.Reviewer = i.resolveReviewers(s)
+	req.AssignedTo = i.resolveAssignedTo(s)
+	return req
+}
+
+// createRequirement 创建用户需求
+func (i *Importer) createRequirement(s *story.Story) (int, *req.Response, error) {
+	req := i.buildRequirementRequest(s)
 
 	i.logger.Debug("创建用户需求请求 - 产品ID: %d, 标题: %s, 模块ID: %d", s.ProductID, s.Title, req.Module)
 
@@ -197,13 +233,13 @@ func (i *Importer) createRequirement(s *story.Story) (int, *req.Response, error)
 	return resp.ID, rsp, nil
 }
 
-// createStory 创建研发需求
-func (i *Importer) createStory(s *story.Story) (int, *req.Response, error) {
+// buildStoryRequest 构建研发需求创建请求
+func (i *Importer) buildStoryRequest(s *story.Story) StoryCreateRequest {
 	req := StoryCreateRequest{
 		ProductID:  s.ProductID,
 		Title:      s.Title,
 		Pri:        s.Priority,
-		Grade:      1,
+		Grade:      storyGrade(s),
 		Spec:       s.Spec,
 		Category:   s.Category,
 		Parent:     s.ParentID,
@@ -212,15 +248,23 @@ func (i *Importer) createStory(s *story.Story) (int, *req.Response, error) {
 		Keywords:   s.Keywords,
 		Verify:     s.Verify,
 		Estimate:   s.Estimate,
+		Plan:       s.Plan,
+		Project:    s.Project,
+		Execution:  s.Execution,
 	}
 
 	// 设置模块ID（优先使用Excel中指定的模块ID，否则使用配置文件默认值）
 	req.Module = i.resolveModule(s.Module)
 
-	// 设置评审人（如果配置了默认评审人）
-	if i.config.GetDefaultReviewer() != "" {
-		req.Reviewer = []string{i.config.GetDefaultReviewer()}
-	}
+	// 设置评审人和指派给（Excel未填写时使用配置默认值）
+	req.Reviewer = i.resolveReviewers(s)
+	req.AssignedTo = i.resolveAssignedTo(s)
+	return req
+}
+
+// createStory 创建研发需求
+func (i *Importer) createStory(s *story.Story) (int, *req.Response, error) {
+	req := i.buildStoryRequest(s)
 
 	i.logger.Debug("创建研发需求请求 - 产品ID: %d, 标题: %s, 模块ID: %d", s.ProductID, s.Title, req.Module)
 
@@ -244,88 +288,161 @@ func (i *Importer) wrapAPIError(operation string, err error, rsp *req.Response)
 	return fmt.Errorf("%s失败: %w (HTTP %d: %s)", operation, err, rsp.StatusCode, rsp.String())
 }
 
-// ImportStories 按层级导入需求（Epic → Requirement → Story）
-// 解析 "@行号" 格式的父需求引用，自动替换为实际创建的禅道ID
+// ImportStories 按拓扑顺序导入需求：先按类型层级（Epic → Requirement → Story），同类型内再按子需求层级(grade)由低到高
+// 解析 "@行号" 格式的父需求引用，自动替换为实际创建的禅道ID；同类型的父子需求（如Story下的子Story）按父需求链计算grade
 // Epic/Requirement创建API不返回ID，需通过产品列表查询获取实际ID，确保父子关系正确建立
+// 父需求导入失败时按 SetParentFailurePolicy 设置的策略处理其后代需求
+// 设置 SetConcurrency 后同一层级内的需求并发导入，结果仍按输入顺序返回
 func (i *Importer) ImportStories(stories []story.Story) []ImportResult {
-	results := make([]ImportResult, len(stories))
-	// 行号到禅道ID的映射（用于解析 @n 引用）
-	rowIDMap := make(map[int]int)
-
-	// 按层级分组并保持原始顺序
-	var epics, requirements, storiesGroup []int // 存储在stories切片中的索引
-	for idx, s := range stories {
-		switch s.Type {
-		case story.StoryTypeEpic:
-			epics = append(epics, idx)
-		case story.StoryTypeRequirement:
-			requirements = append(requirements, idx)
-		case story.StoryTypeStory:
-			storiesGroup = append(storiesGroup, idx)
+	// 无法解析的 "@引用键" 保持原样，导入到该行时报告失败（见 resolveParentRef）
+	for _, issue := range story.ResolveKeyRefs(stories) {
+		i.logger.Error("%s", issue.String())
+	}
+	story.AssignGrades(stories)
+	run := newImportRun(stories)
+
+	// 按类型层级和子需求层级分批，批内保持原始顺序
+	waves := importWaves(stories)
+
+	typeCount := make(map[story.StoryType]int)
+	for _, s := range stories {
+		typeCount[s.Type]++
+	}
+	i.logger.Info("开始层级导入: %d个业务需求 → %d个用户需求 → %d个研发需求，共 %d 个阶段",
+		typeCount[story.StoryTypeEpic], typeCount[story.StoryTypeRequirement], typeCount[story.StoryTypeStory], len(waves))
+
+	for n, wave := range waves {
+		first := &stories[wave[0]]
+		if grade := storyGrade(first); grade > 1 {
+			i.logger.Info("========== 阶段%d: 导入%s 第%d级子需求 ==========", n+1, first.GetTypeString(), grade)
+		} else {
+			i.logger.Info("========== 阶段%d: 导入%s ==========", n+1, first.GetTypeString())
 		}
+		i.importLevel(run, wave)
 	}
 
-	i.logger.Info("开始层级导入: %d个业务需求 → %d个用户需求 → %d个研发需求",
-		len(epics), len(requirements), len(storiesGroup))
-
-	// 第一阶段：导入 Epic
-	i.logger.Info("========== 阶段1: 导入业务需求(Epic) ==========")
-	for _, idx := range epics {
-		s := &stories[idx]
-		i.resolveParentRef(s, rowIDMap)
-		results[idx] = i.ImportStory(s)
-		if results[idx].Success {
-			// Epic创建API不返回ID，通过产品列表查询获取实际ID
-			actualID := i.resolveCreatedID(s.Type, s.ProductID, s.Title, results[idx].StoryID)
-			if actualID > 0 {
-				results[idx].StoryID = actualID
-				rowIDMap[s.RowIndex] = actualID
-			} else {
-				rowIDMap[s.RowIndex] = results[idx].StoryID
-			}
+	// 汇总统计
+	successCount, skippedCount := 0, 0
+	for _, result := range run.results {
+		if result.Success {
+			successCount++
+		} else if result.Skipped {
+			skippedCount++
+		}
+	}
+	i.logger.Info("层级导入完成，成功: %d，失败: %d，跳过: %d", successCount, len(stories)-successCount-skippedCount, skippedCount)
+
+	return run.results
+}
+
+// importAt 导入stories[idx]并记录其实际禅道ID到rowIDMap
+// 检查点日志中已创建的行直接复用日志中的ID，不再重复创建
+func (i *Importer) importAt(run *importRun, idx int) {
+	s := &run.stories[idx]
+	results := run.results
+
+	if i.journal != nil {
+		if e, ok := i.journal.Created(s.RowIndex); ok {
+			i.logger.Info("行%d 已在检查点日志中记录为已创建(ID: %d)，跳过: %s", s.RowIndex, e.StoryID, s.Title)
+			results[idx] = ImportResult{Success: true, StoryID: e.StoryID, StoryType: string(s.Type), Resumed: true}
+			run.setRowID(s.RowIndex, e.StoryID)
+			return
 		}
 	}
 
-	// 第二阶段：解析 Requirement 的父引用并导入
-	i.logger.Info("========== 阶段2: 导入用户需求(Requirement) ==========")
-	for _, idx := range requirements {
-		s := &stories[idx]
-		i.resolveParentRef(s, rowIDMap)
-		results[idx] = i.ImportStory(s)
-		if results[idx].Success {
-			// Requirement创建API不返回ID，通过产品列表查询获取实际ID
-			actualID := i.resolveCreatedID(s.Type, s.ProductID, s.Title, results[idx].StoryID)
-			if actualID > 0 {
-				results[idx].StoryID = actualID
-				rowIDMap[s.RowIndex] = actualID
-			} else {
-				rowIDMap[s.RowIndex] = results[idx].StoryID
+	if i.checkParentFailure(run, idx) {
+		return
+	}
+	defer i.checkAbort(run, idx)
+
+	err := i.resolveParentRef(s, run)
+	if err == nil {
+		err = i.resolveGrade(run, s)
+	}
+	if err != nil {
+		i.logger.Error("行%d %v: %s", s.RowIndex, err, s.Title)
+		results[idx] = ImportResult{StoryType: string(s.Type), Error: err}
+		i.recordJournal(s, results[idx])
+		return
+	}
+
+	// 幂等导入：外部ID已映射的行执行更新而不是新建
+	if i.upsertMap != nil && s.ExternalID != "" {
+		if rec, ok := i.upsertMap.Get(s.ExternalID); ok {
+			results[idx] = i.upsertExisting(s, rec)
+			if results[idx].Success {
+				run.setRowID(s.RowIndex, results[idx].StoryID)
 			}
+			i.recordJournal(s, results[idx])
+			return
 		}
 	}
 
-	// 第三阶段：解析 Story 的父引用并导入
-	i.logger.Info("========== 阶段3: 导入研发需求(Story) ==========")
-	for _, idx := range storiesGroup {
-		s := &stories[idx]
-		i.resolveParentRef(s, rowIDMap)
-		results[idx] = i.ImportStory(s)
-		// Story创建API会返回ID，无需额外查询
-		if results[idx].Success {
-			rowIDMap[s.RowIndex] = results[idx].StoryID
+	results[idx] = i.createAndResolve(s)
+	if results[idx].Success {
+		run.setRowID(s.RowIndex, results[idx].StoryID)
+		if i.upsertMap != nil && s.ExternalID != "" && results[idx].StoryID > 0 {
+			i.rememberCreated(s, results[idx].StoryID)
 		}
 	}
 
-	// 汇总统计
-	successCount := 0
-	for _, result := range results {
-		if result.Success {
-			successCount++
+	i.recordJournal(s, results[idx])
+}
+
+// createAndResolve 创建需求并获取其实际禅道ID
+// Epic/Requirement创建API不返回ID，通过产品列表查询获取实际ID；Story创建API会返回ID，无需额外查询
+// 并发导入时Epic/Requirement的创建与ID解析串行执行，避免同时出现多个新ID时相互混淆
+func (i *Importer) createAndResolve(s *story.Story) ImportResult {
+	if s.Type == story.StoryTypeEpic || s.Type == story.StoryTypeRequirement {
+		i.ids.create.Lock()
+		defer i.ids.create.Unlock()
+		i.ensureSnapshot(s.ProductID)
+	}
+	result := i.ImportStory(s)
+	if result.Success {
+		if actualID := i.resolveCreatedID(s, result.StoryID); actualID > 0 {
+			result.StoryID = actualID
+		} else if result.StoryID <= 0 {
+			// 无法确定实际ID时不能记录为已创建：子需求无法关联，回滚也无法删除
+			result.Success = false
+			result.Error = fmt.Errorf("需求已创建，但无法确定其禅道ID，请在禅道中核对该需求，避免重复导入")
+			i.logger.Error("行%d %v: %s", s.RowIndex, result.Error, s.Title)
 		}
 	}
-	i.logger.Info("层级导入完成，成功: %d，失败: %d", successCount, len(stories)-successCount)
+	return result
+}
 
-	return results
+// recordJournal 将导入结果写入检查点日志（未设置日志时忽略）
+func (i *Importer) recordJournal(s *story.Story, result ImportResult) {
+	if i.journal == nil {
+		return
+	}
+	entry := JournalEntry{
+		RowIndex:  s.RowIndex,
+		StoryID:   result.StoryID,
+		StoryType: string(s.Type),
+		ProductID: s.ProductID,
+		Title:     s.Title,
+		Status:    JournalStatusCreated,
+	}
+	switch result.Action {
+	case ImportActionUpdated:
+		entry.Status = JournalStatusUpdated
+	case ImportActionUnchanged:
+		entry.Status = JournalStatusUnchanged
+	}
+	if result.Skipped {
+		entry.Status = JournalStatusSkipped
+		entry.Error = result.SkipReason
+	} else if !result.Success {
+		entry.Status = JournalStatusFailed
+		if result.Error != nil {
+			entry.Error = result.Error.Error()
+		}
+	}
+	if err := i.journal.Record(entry); err != nil {
+		i.logger.Error("写入检查点日志失败(行%d): %v", s.RowIndex, err)
+	}
 }
 
 // resolveModule 解析模块ID，优先使用Excel中指定的模块ID，否则降级使用配置文件默认值
@@ -347,129 +464,91 @@ func (i *Importer) resolveModule(excelModule int) int {
 	return 0
 }
 
-// resolveCreatedID 查询禅道获取需求创建后的实际ID
-// Epic和Requirement的创建API不返回ID，需要通过产品列表按标题匹配查询
-// Story类型的创建API会返回ID，直接使用即可
-// 去重策略：Epic API会返回关联的Requirement和Story，Requirement API会返回关联的Story
-// 因此查询时需要排除低层级中已存在的ID，确保匹配到正确类型的实际ID
-func (i *Importer) resolveCreatedID(sType story.StoryType, productID int, title string, createRespID int) int {
-	// Story类型创建API会返回ID，直接使用
-	if sType == story.StoryTypeStory && createRespID > 0 {
-		return createRespID
-	}
-
-	// Epic/Requirement创建API不返回ID，通过产品列表按标题匹配查询
-	// 需要去重：先获取低层级列表的ID，在高层级列表中排除这些ID
-	switch sType {
-	case story.StoryTypeEpic:
-		// 查询Epic时需排除Story和Requirement中已有的ID
-		seenIDs := make(map[int]bool)
-
-		stories, err := i.storyCreator.ProductsListAll(productID)
-		if err != nil {
-			i.logger.Info("查询产品研发需求列表失败(产品ID=%d): %v", productID, err)
-		} else {
-			for _, s := range stories {
-				seenIDs[s.ID] = true
-			}
+// resolveReviewers 返回需求的评审人：优先使用Excel"评审人"列，否则使用配置的默认评审人
+func (i *Importer) resolveReviewers(s *story.Story) []string {
+	reviewers := s.Reviewers
+	if len(reviewers) == 0 {
+		if reviewer := i.config.GetDefaultReviewer(); reviewer != "" {
+			reviewers = []string{reviewer}
 		}
-
-		requirements, err := i.reqCreator.ProductsListAll(productID)
-		if err != nil {
-			i.logger.Info("查询产品用户需求列表失败(产品ID=%d): %v", productID, err)
-		} else {
-			for _, r := range requirements {
-				seenIDs[r.ID] = true
-			}
-		}
-
-		epics, err := i.epicCreator.ProductsListAll(productID)
-		if err != nil {
-			i.logger.Info("查询产品业务需求列表失败(产品ID=%d): %v", productID, err)
-			return createRespID
-		}
-		for _, e := range epics {
-			if seenIDs[e.ID] {
-				continue // 跳过已在Story或Requirement中出现的ID
-			}
-			if e.Title == title {
-				i.logger.Info("通过产品列表查询到业务需求实际ID: %d (标题: %s)", e.ID, title)
-				return e.ID
-			}
-		}
-		i.logger.Info("在产品业务需求列表中未找到匹配项(产品ID=%d, 标题=%s)", productID, title)
-
-	case story.StoryTypeRequirement:
-		// 查询Requirement时需排除Story中已有的ID
-		seenIDs := make(map[int]bool)
-
-		stories, err := i.storyCreator.ProductsListAll(productID)
-		if err != nil {
-			i.logger.Info("查询产品研发需求列表失败(产品ID=%d): %v", productID, err)
-		} else {
-			for _, s := range stories {
-				seenIDs[s.ID] = true
-			}
-		}
-
-		requirements, err := i.reqCreator.ProductsListAll(productID)
-		if err != nil {
-			i.logger.Info("查询产品用户需求列表失败(产品ID=%d): %v", productID, err)
-			return createRespID
-		}
-		for _, r := range requirements {
-			if seenIDs[r.ID] {
-				continue // 跳过已在Story中出现的ID
-			}
-			if r.Title == title {
-				i.logger.Info("通过产品列表查询到用户需求实际ID: %d (标题: %s)", r.ID, title)
-				return r.ID
-			}
-		}
-		i.logger.Info("在产品用户需求列表中未找到匹配项(产品ID=%d, 标题=%s)", productID, title)
 	}
+	if len(reviewers) == 0 {
+		return nil
+	}
+	accounts := make([]string, len(reviewers))
+	for n, reviewer := range reviewers {
+		accounts[n] = i.resolveAccount(reviewer)
+	}
+	return accounts
+}
 
-	// 如果创建API返回了ID（某些版本可能支持），直接使用
-	if createRespID > 0 {
-		return createRespID
+// resolveAssignedTo 返回需求的指派给：优先使用Excel"指派给"列，否则使用配置的默认指派人
+func (i *Importer) resolveAssignedTo(s *story.Story) string {
+	if s.AssignedTo != "" {
+		return i.resolveAccount(s.AssignedTo)
 	}
+	return i.resolveAccount(i.config.GetDefaultAssignedTo())
+}
 
-	i.logger.Info("无法获取需求实际ID(标题=%s)，创建返回ID=%d", title, createRespID)
-	return 0
+// resolveAccount 通过用户索引将姓名解析为账号；未设置索引、值为空或无法唯一解析时原样返回（导入前的校验会报告无法解析的姓名）
+func (i *Importer) resolveAccount(name string) string {
+	if i.users == nil || name == "" {
+		return name
+	}
+	account, err := i.users.Resolve(name)
+	if err != nil {
+		i.logger.Error("%v", err)
+		return name
+	}
+	return account
 }
 
 // resolveParentRef 解析父需求引用，将 "@行号" 格式替换为实际的禅道ID
-func (i *Importer) resolveParentRef(s *story.Story, rowIDMap map[int]int) {
-	if s.ParentRef == "" {
-		return
+// "@引用键" 在导入开始时已改写为 "@行号"（见 story.ResolveKeyRefs），仍为引用键格式说明引用键不存在或重复
+// 引用无法解析时返回错误，该行导入失败，而不是创建为没有父需求的需求；
+// 唯一的例外是 orphan 策略下父需求行导入失败，此时按策略创建为无父需求的需求
+func (i *Importer) resolveParentRef(s *story.Story, run *importRun) error {
+	if s.ParentTitle != "" && s.ParentID == 0 {
+		return fmt.Errorf("父需求 %s 未解析为禅道ID，请先使用 ParentTitleResolver 在产品已有需求中查找", s.ParentRef)
 	}
-
-	// 检查是否为 "@行号" 格式
-	ref := strings.TrimPrefix(s.ParentRef, "@")
-	if ref == s.ParentRef {
-		// 不是 @ 格式，ParentID 已经在解析时设置
-		return
+	rowNum, isRowRef, err := story.ParseRowRef(s.ParentRef)
+	if !isRowRef {
+		// 不是 @ 格式，ParentID 已经在解析时或导入前（"#title:标题"）设置
+		return nil
+	}
+	if _, isKeyRef := story.ParseKeyRef(s.ParentRef); isKeyRef {
+		return fmt.Errorf("父需求引用 %s 的引用键不存在或重复", s.ParentRef)
 	}
-
-	rowNum, err := strconv.Atoi(ref)
 	if err != nil {
-		i.logger.Error("无效的父需求引用格式: %s（行%d），应为 @行号", s.ParentRef, s.RowIndex)
-		return
+		return err
 	}
 
-	parentID, ok := rowIDMap[rowNum]
+	parentID, ok := run.rowID(rowNum)
 	if !ok {
-		i.logger.Error("父需求引用 @%d 未找到对应的禅道ID（行%d），该行可能导入失败", rowNum, s.RowIndex)
-		return
+		if root, failed := run.failedParent(s); failed {
+			// skip/abort 策略已在 checkParentFailure 中处理，此处只有 orphan 策略
+			i.logger.Error("行%d 父需求 %s 未能导入（行%d 导入失败），按 orphan 策略创建为无父需求的需求", s.RowIndex, parentLabel(s), root)
+			return nil
+		}
+		return fmt.Errorf("父需求引用 %s 未找到对应的禅道ID（指向的行不在本次导入中）", parentLabel(s))
 	}
 
 	s.ParentID = parentID
-	i.logger.Debug("行%d 父需求引用 @%d 解析为禅道ID: %d", s.RowIndex, rowNum, parentID)
+	i.logger.Debug("行%d 父需求引用 %s 解析为禅道ID: %d", s.RowIndex, parentLabel(s), parentID)
+	return nil
+}
+
+// parentLabel 父需求引用的显示文本："@引用键" 显示为 "@引用键（行n）"，其他引用显示原值
+func parentLabel(s *story.Story) string {
+	if s.ParentKey == "" {
+		return s.ParentRef
+	}
+	return fmt.Sprintf("@%s（行%s）", s.ParentKey, strings.TrimPrefix(s.ParentRef, "@"))
 }
 
 // GenerateReport 生成导入报告
 func (i *Importer) GenerateReport(results []ImportResult) string {
-	var totalCount, successCount int
+	var totalCount, successCount, resumedCount, updatedCount, unchangedCount, skippedCount int
 	var totalTime time.Duration
 	var report string
 
@@ -477,10 +556,29 @@ func (i *Importer) GenerateReport(results []ImportResult) string {
 
 	// 统计结果
 	for idx, result := range results {
-		if result.Success {
+		if result.Resumed {
+			successCount++
+			resumedCount++
+			report += fmt.Sprintf("↺ 需求 #%d 已在之前的运行中创建，跳过 (ID: %d)\n",
+				idx+1, result.StoryID)
+		} else if result.Success && result.Action == ImportActionUpdated {
+			successCount++
+			updatedCount++
+			report += fmt.Sprintf("✓ 需求 #%d 已存在，更新成功 (ID: %d, 耗时: %v)\n",
+				idx+1, result.StoryID, result.ElapsedTime)
+		} else if result.Success && result.Action == ImportActionUnchanged {
+			successCount++
+			unchangedCount++
+			report += fmt.Sprintf("= 需求 #%d 已存在且与上次导入相比无变化 (ID: %d)\n",
+				idx+1, result.StoryID)
+		} else if result.Success {
 			successCount++
 			report += fmt.Sprintf("✓ 需求 #%d 导入成功 (ID: %d, 耗时: %v)\n",
 				idx+1, result.StoryID, result.ElapsedTime)
+		} else if result.Skipped {
+			skippedCount++
+			report += fmt.Sprintf("⊘ 需求 #%d 已跳过: %s\n",
+				idx+1, result.SkipReason)
 		} else {
 			report += fmt.Sprintf("✗ 需求 #%d 导入失败: %v\n",
 				idx+1, result.Error)
@@ -496,7 +594,17 @@ func (i *Importer) GenerateReport(results []ImportResult) string {
 	report += "\n总计统计:\n"
 	report += fmt.Sprintf("- 总需求数: %d\n", totalCount)
 	report += fmt.Sprintf("- 成功导入: %d\n", successCount)
-	report += fmt.Sprintf("- 失败数量: %d\n", totalCount-successCount)
+	report += fmt.Sprintf("- 失败数量: %d\n", totalCount-successCount-skippedCount)
+	if skippedCount > 0 {
+		report += fmt.Sprintf("- 跳过数量: %d\n", skippedCount)
+	}
+	if resumedCount > 0 {
+		report += fmt.Sprintf("- 续传跳过: %d\n", resumedCount)
+	}
+	if updatedCount > 0 || unchangedCount > 0 {
+		report += fmt.Sprintf("- 其中新建: %d，更新: %d，无变化: %d\n",
+			successCount-resumedCount-updatedCount-unchangedCount, updatedCount, unchangedCount)
+	}
 	report += fmt.Sprintf("- 总耗时: %v\n", totalTime)
 	if totalCount > 0 {
 		report += fmt.Sprintf("- 平均耗时: %v\n", totalTime/time.Duration(totalCount))
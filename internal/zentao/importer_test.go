@@ -82,6 +82,30 @@ func TestImporter_ImportStory_APIFailure(t *testing.T) {
 	}
 }
 
+func TestImporter_BuildRequest_AssigneeAndReviewers(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	importer := NewImporterWithMocks(log, nil, nil, nil, &mockConfig{reviewer: "tester", assignee: "owner"})
+
+	req := importer.buildStoryRequest(&story.Story{Title: "使用默认值"})
+	if req.AssignedTo != "owner" || len(req.Reviewer) != 1 || req.Reviewer[0] != "tester" {
+		t.Errorf("未填写时应使用配置默认值, 得到 指派给=%q 评审人=%v", req.AssignedTo, req.Reviewer)
+	}
+
+	epic := importer.buildEpicRequest(&story.Story{Title: "按行填写", AssignedTo: "zhangsan", Reviewers: []string{"lisi", "wangwu"}})
+	if epic.AssignedTo != "zhangsan" || len(epic.Reviewer) != 2 || epic.Reviewer[0] != "lisi" || epic.Reviewer[1] != "wangwu" {
+		t.Errorf("应使用Excel中的值, 得到 指派给=%q 评审人=%v", epic.AssignedTo, epic.Reviewer)
+	}
+
+	// 设置用户索引后，姓名解析为账号
+	importer = NewImporterWithMocks(log, nil, nil, nil, &mockConfig{reviewer: "测试"})
+	importer.SetUserIndex(NewUserIndex([]User{{Account: "tester", Realname: "测试"}, {Account: "zhangsan", Realname: "张三"}}))
+	req = importer.buildStoryRequest(&story.Story{Title: "按姓名填写", AssignedTo: "张三"})
+	if req.AssignedTo != "zhangsan" || len(req.Reviewer) != 1 || req.Reviewer[0] != "tester" {
+		t.Errorf("姓名应解析为账号, 得到 指派给=%q 评审人=%v", req.AssignedTo, req.Reviewer)
+	}
+}
+
 func TestImporter_ImportStory_RequirementModuleFallback(t *testing.T) {
 	var buf bytes.Buffer
 	log := logger.NewLoggerWithWriter(&buf)
@@ -132,17 +156,25 @@ func TestImporter_ImportStories_EpicRequirementResolveID(t *testing.T) {
 	var buf bytes.Buffer
 	log := logger.NewLoggerWithWriter(&buf)
 
+	// 列表只包含已创建的需求（创建前的快照为空）
+	epicCreated, reqCreated := false, false
+
 	// Epic创建API不返回ID（ID=0），需通过列表查询获取
 	mockEpic := &mockEpicService{
 		createFn: func(req EpicCreateRequest) (*EpicCreateResponse, *req.Response, error) {
+			epicCreated = true
 			return &EpicCreateResponse{Status: "success", ID: 0}, nil, nil // 模拟API不返回ID
 		},
 		// Epic列表包含关联的Requirement和Story
 		listFn: func(productID int) ([]EpicListItem, error) {
-			return []EpicListItem{
-				{ID: 601, Title: "用户需求B", Product: 1}, // 实际是Requirement，Epic API也会返回
-				{ID: 501, Title: "业务需求A", Product: 1}, // 真正的Epic
-			}, nil
+			var items []EpicListItem
+			if reqCreated {
+				items = append(items, EpicListItem{ID: 601, Title: "用户需求B", Product: 1}) // 实际是Requirement，Epic API也会返回
+			}
+			if epicCreated {
+				items = append(items, EpicListItem{ID: 501, Title: "业务需求A", Product: 1}) // 真正的Epic
+			}
+			return items, nil
 		},
 		deleteFn: func(id int) (map[string]interface{}, *req.Response, error) {
 			return nil, nil, nil
@@ -156,10 +188,14 @@ func TestImporter_ImportStories_EpicRequirementResolveID(t *testing.T) {
 			if req.Parent != 501 {
 				t.Errorf("期望Requirement的Parent=501, 得到 %d", req.Parent)
 			}
+			reqCreated = true
 			return &RequirementCreateResponse{Status: "success", ID: 0}, nil, nil
 		},
 		// Requirement列表包含关联的Story
 		listFn: func(productID int) ([]RequirementListItem, error) {
+			if !reqCreated {
+				return nil, nil
+			}
 			return []RequirementListItem{
 				{ID: 601, Title: "用户需求B", Product: 1}, // 真正的Requirement
 			}, nil
@@ -201,9 +237,7 @@ func TestImporter_ImportStories_EpicRequirementResolveID(t *testing.T) {
 		t.Fatalf("期望3个结果, 得到 %d", len(results))
 	}
 
-	// Epic: 创建返回ID=0, 通过列表查询去重后得到ID=501
-	// Epic列表中ID=601("用户需求B")在Story列表中未出现，但ID=601标题是"用户需求B"不匹配"业务需求A"
-	// 所以匹配到ID=501("业务需求A")
+	// Epic: 创建返回ID=0, 创建后Epic列表中出现快照之外的新ID=501("业务需求A")
 	if !results[0].Success {
 		t.Fatal("Epic应导入成功")
 	}
@@ -264,6 +298,40 @@ func TestImporter_ImportStories_Batch(t *testing.T) {
 	}
 }
 
+func TestImporter_ImportStories_UnresolvedParentRef(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	parents := make(map[string]int)
+	mockStory := &mockStoryService{
+		createFn: func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			parents[req.Title] = req.Parent
+			return &StoryCreateResponse{Status: "success", ID: 100 + len(parents)}, nil, nil
+		},
+	}
+	importer := NewImporterWithMocks(log, nil, nil, mockStory, &mockConfig{reviewer: "tester"})
+
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeStory, Title: "父", ProductID: 1, RefKey: "PARENT", RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "引用键", ProductID: 1, ParentRef: "@PARENT", RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "引用键不存在", ProductID: 1, ParentRef: "@NONE", RowIndex: 3},
+		{Type: story.StoryTypeStory, Title: "行不存在", ProductID: 1, ParentRef: "@9", RowIndex: 4},
+	})
+
+	if !results[1].Success || parents["引用键"] != results[0].StoryID {
+		t.Errorf("@PARENT 应解析为行1的禅道ID %d, 得到 %+v (parent=%d)", results[0].StoryID, results[1], parents["引用键"])
+	}
+	// 无法解析的父需求引用导致该行失败，而不是创建为没有父需求的需求
+	for idx, title := range map[int]string{2: "引用键不存在", 3: "行不存在"} {
+		if results[idx].Success || results[idx].Error == nil {
+			t.Errorf("行%d 父需求无法解析时应导入失败, 得到 %+v", idx+1, results[idx])
+		}
+		if _, created := parents[title]; created {
+			t.Errorf("行%d 不应被创建", idx+1)
+		}
+	}
+}
+
 func TestImporter_GenerateReport(t *testing.T) {
 	var buf bytes.Buffer
 	log := logger.NewLoggerWithWriter(&buf)
@@ -0,0 +1,219 @@
+// Package zentao 封装禅道API客户端 - 导入检查点日志
+package zentao
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// JournalDir 检查点日志的默认目录
+const JournalDir = "journal"
+
+// 日志条目状态
+const (
+	JournalStatusCreated    = "created"    // 已在禅道中创建
+	JournalStatusFailed     = "failed"     // 创建失败，续传时会重试
+	JournalStatusUpdated    = "updated"    // 幂等导入时已存在并已更新
+	JournalStatusUnchanged  = "unchanged"  // 幂等导入时已存在且无变化
+	JournalStatusSkipped    = "skipped"    // 因父需求导入失败而跳过，续传时会重试
+	JournalStatusRolledBack = "rolledback" // 已通过 -action rollback 删除
+)
+
+// JournalEntry 检查点日志条目，每个需求导入完成后追加一行JSON
+type JournalEntry struct {
+	RowIndex  int    `json:"row"`             // Excel数据行号
+	StoryID   int    `json:"id"`              // 禅道实际ID
+	StoryType string `json:"type"`            // 需求类型
+	ProductID int    `json:"product"`         // 产品ID
+	Title     string `json:"title"`           // 标题（续传时用于校验Excel是否被修改）
+	Status    string `json:"status"`          // 状态
+	Error     string `json:"error,omitempty"` // 失败原因
+	Time      string `json:"time"`            // 记录时间
+}
+
+// Journal 导入检查点日志（JSON Lines格式），用于中断后续传
+// 同一行号可能出现多条记录（如先失败后续传成功），以最后一条为准
+type Journal struct {
+	mu      sync.Mutex
+	file    *os.File
+	path    string
+	entries map[int]JournalEntry // 行号 -> 最新条目
+}
+
+// NewRunID 生成本次运行的ID：当前时间加随机后缀（如 20260101-120000-3f9a1c），同一秒内开始的多次导入也不会重复
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		return time.Now().Format("20060102-150405.000000000")
+	}
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// JournalPath 返回运行ID对应的检查点日志路径
+func JournalPath(runID string) string {
+	return filepath.Join(JournalDir, runID+".jsonl")
+}
+
+// CreateJournal 在目录 dir 下为新的导入运行创建检查点日志（文件名为新的运行ID）
+// 文件以独占方式创建，绝不会打开其他运行的日志，否则会把其他运行已创建的行当作本次已创建而跳过
+func CreateJournal(dir string) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建检查点日志目录失败: %w", err)
+	}
+	for attempt := 0; ; attempt++ {
+		path := filepath.Join(dir, NewRunID()+".jsonl")
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+		if errors.Is(err, os.ErrExist) && attempt < 3 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("创建检查点日志失败: %w", err)
+		}
+		return newJournal(file, path, nil), nil
+	}
+}
+
+// OpenJournal 打开已有的检查点日志（用于续传和回滚，不存在时创建），已有条目会被加载，新条目追加到文件末尾
+func OpenJournal(path string) (*Journal, error) {
+	entries, err := LoadJournal(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建检查点日志目录失败: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打开检查点日志失败: %w", err)
+	}
+	return newJournal(file, path, entries), nil
+}
+
+// newJournal 以已加载的条目创建检查点日志
+func newJournal(file *os.File, path string, entries []JournalEntry) *Journal {
+	j := &Journal{
+		file:    file,
+		path:    path,
+		entries: make(map[int]JournalEntry),
+	}
+	for _, e := range entries {
+		j.entries[e.RowIndex] = e
+	}
+	return j
+}
+
+// LoadJournal 读取检查点日志中的全部条目（按写入顺序）
+func LoadJournal(path string) ([]JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e JournalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("解析检查点日志第%d行失败: %w", lineNum, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取检查点日志失败: %w", err)
+	}
+	return entries, nil
+}
+
+// Path 返回检查点日志文件路径
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Record 追加一条记录并立即落盘，保证进程中断后已完成的行不会丢失
+func (j *Journal) Record(entry JournalEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if entry.Time == "" {
+		entry.Time = time.Now().Format(time.RFC3339)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化检查点日志失败: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入检查点日志失败: %w", err)
+	}
+	if err := j.file.Sync(); err != nil {
+		return fmt.Errorf("同步检查点日志失败: %w", err)
+	}
+	j.entries[entry.RowIndex] = entry
+	return nil
+}
+
+// Created 返回行号对应的已创建条目（不存在或未成功创建时返回false）
+func (j *Journal) Created(rowIndex int) (JournalEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e, ok := j.entries[rowIndex]
+	if !ok || e.Status != JournalStatusCreated {
+		return JournalEntry{}, false
+	}
+	return e, true
+}
+
+// CreatedCount 返回已成功创建的行数
+func (j *Journal) CreatedCount() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	count := 0
+	for _, e := range j.entries {
+		if e.Status == JournalStatusCreated {
+			count++
+		}
+	}
+	return count
+}
+
+// Mismatches 校验已创建的行与当前Excel是否一致（行号相同但类型或标题不同说明Excel已被修改）
+func (j *Journal) Mismatches(stories []story.Story) []string {
+	var mismatches []string
+	for _, s := range stories {
+		e, ok := j.Created(s.RowIndex)
+		if !ok {
+			continue
+		}
+		if e.Title != s.Title || e.StoryType != string(s.Type) {
+			mismatches = append(mismatches, fmt.Sprintf("行%d: 检查点日志记录为 [%s] %s，当前Excel为 [%s] %s",
+				s.RowIndex, e.StoryType, e.Title, s.Type, s.Title))
+		}
+	}
+	return mismatches
+}
+
+// Close 关闭检查点日志文件
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
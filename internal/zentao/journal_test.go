@@ -0,0 +1,126 @@
+package zentao
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestJournal_RecordAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal", "run.jsonl")
+
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("打开检查点日志失败: %v", err)
+	}
+	_ = j.Record(JournalEntry{RowIndex: 1, StoryID: 10, StoryType: "epic", Title: "E", Status: JournalStatusCreated})
+	_ = j.Record(JournalEntry{RowIndex: 2, StoryType: "story", Title: "S", Status: JournalStatusFailed, Error: "boom"})
+	j.Close()
+
+	// 重新打开后追加：行2续传成功
+	j, err = OpenJournal(path)
+	if err != nil {
+		t.Fatalf("重新打开检查点日志失败: %v", err)
+	}
+	defer j.Close()
+
+	if e, ok := j.Created(1); !ok || e.StoryID != 10 {
+		t.Errorf("行1应为已创建(ID=10), 得到 %+v, %v", e, ok)
+	}
+	if _, ok := j.Created(2); ok {
+		t.Error("行2失败，不应视为已创建")
+	}
+	_ = j.Record(JournalEntry{RowIndex: 2, StoryID: 20, StoryType: "story", Title: "S", Status: JournalStatusCreated})
+	if j.CreatedCount() != 2 {
+		t.Errorf("期望已创建2行, 得到 %d", j.CreatedCount())
+	}
+
+	entries, err := LoadJournal(path)
+	if err != nil {
+		t.Fatalf("读取检查点日志失败: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("期望3条记录, 得到 %d", len(entries))
+	}
+
+	mismatches := j.Mismatches([]story.Story{
+		{Type: story.StoryTypeEpic, Title: "E", RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "改过的标题", RowIndex: 2},
+	})
+	if len(mismatches) != 1 {
+		t.Errorf("期望1处不一致, 得到 %v", mismatches)
+	}
+}
+
+func TestCreateJournal_UniqueRun(t *testing.T) {
+	dir := t.TempDir()
+
+	first, err := CreateJournal(dir)
+	if err != nil {
+		t.Fatalf("创建检查点日志失败: %v", err)
+	}
+	defer first.Close()
+	_ = first.Record(JournalEntry{RowIndex: 1, StoryID: 10, StoryType: "epic", Title: "E", Status: JournalStatusCreated})
+
+	// 同一秒内开始的另一次导入使用新的日志，不会把第一次运行已创建的行当作已创建
+	second, err := CreateJournal(dir)
+	if err != nil {
+		t.Fatalf("创建检查点日志失败: %v", err)
+	}
+	defer second.Close()
+	if second.Path() == first.Path() {
+		t.Fatalf("两次运行使用了相同的检查点日志: %s", first.Path())
+	}
+	if _, ok := second.Created(1); ok {
+		t.Error("新运行的检查点日志不应包含其他运行的记录")
+	}
+}
+
+func TestImporter_ImportStories_Resume(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	j, err := OpenJournal(filepath.Join(t.TempDir(), "run.jsonl"))
+	if err != nil {
+		t.Fatalf("打开检查点日志失败: %v", err)
+	}
+	defer j.Close()
+	_ = j.Record(JournalEntry{RowIndex: 1, StoryID: 501, StoryType: "epic", Title: "E", Status: JournalStatusCreated})
+
+	mockEpic := &mockEpicService{
+		createFn: func(r EpicCreateRequest) (*EpicCreateResponse, *req.Response, error) {
+			t.Error("已创建的Epic不应重复创建")
+			return &EpicCreateResponse{Status: "success"}, nil, nil
+		},
+	}
+	mockStorySvc := &mockStoryService{
+		createFn: func(r StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			if r.Parent != 501 {
+				t.Errorf("期望Story的Parent=501(来自检查点日志), 得到 %d", r.Parent)
+			}
+			return &StoryCreateResponse{Status: "success", ID: 701}, nil, nil
+		},
+	}
+
+	importer := NewImporterWithMocks(log, mockEpic, nil, mockStorySvc, &mockConfig{reviewer: "tester"})
+	importer.SetJournal(j)
+
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "S", ProductID: 1, ParentRef: "@1", RowIndex: 2},
+	})
+
+	if !results[0].Resumed || results[0].StoryID != 501 {
+		t.Errorf("Epic应从检查点日志恢复, 得到 %+v", results[0])
+	}
+	if !results[1].Success || results[1].StoryID != 701 {
+		t.Errorf("Story应导入成功, 得到 %+v", results[1])
+	}
+	if e, ok := j.Created(2); !ok || e.StoryID != 701 {
+		t.Errorf("Story的导入结果应写入检查点日志, 得到 %+v", e)
+	}
+}
@@ -0,0 +1,217 @@
+// Package zentao 封装禅道API客户端 - 模块路径解析与自动创建
+package zentao
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// ModulePath 产品下的模块路径（如 "支付/退款/对账"）
+type ModulePath struct {
+	ProductID int
+	Path      string
+}
+
+// String 返回模块路径的可读描述
+func (m ModulePath) String() string {
+	return fmt.Sprintf("产品%d: %s", m.ProductID, m.Path)
+}
+
+// ModuleResolver 将Excel模块列或 defaultModule 中填写的模块路径按产品模块树解析为模块ID，可选自动创建缺失的模块
+type ModuleResolver struct {
+	logger  *logger.Logger
+	modules ModuleManager
+
+	trees map[int]map[string]int // 产品ID -> 模块路径 -> 模块ID
+	errs  map[int]error          // 产品ID -> 模块查询错误
+}
+
+// NewModuleResolver 创建新的模块路径解析器
+func NewModuleResolver(client *Client, log *logger.Logger) *ModuleResolver {
+	return NewModuleResolverWithMocks(log, client.Module)
+}
+
+// NewModuleResolverWithMocks 创建模块路径解析器（用于测试，直接注入mock实现）
+func NewModuleResolverWithMocks(log *logger.Logger, modules ModuleManager) *ModuleResolver {
+	return &ModuleResolver{
+		logger:  log,
+		modules: modules,
+		trees:   make(map[int]map[string]int),
+		errs:    make(map[int]error),
+	}
+}
+
+// Missing 返回在产品模块树中不存在的模块路径（按产品和路径排序去重），模块列表查询失败的产品不计入
+func (r *ModuleResolver) Missing(stories []story.Story, defaultPath string) []ModulePath {
+	seen := make(map[ModulePath]bool)
+	var missing []ModulePath
+	for _, s := range stories {
+		path := modulePathOf(&s, defaultPath)
+		if path == "" {
+			continue
+		}
+		tree, err := r.tree(s.ProductID)
+		if err != nil {
+			continue
+		}
+		key := ModulePath{ProductID: s.ProductID, Path: path}
+		if _, ok := tree[path]; !ok && !seen[key] {
+			seen[key] = true
+			missing = append(missing, key)
+		}
+	}
+	sort.Slice(missing, func(a, b int) bool {
+		if missing[a].ProductID != missing[b].ProductID {
+			return missing[a].ProductID < missing[b].ProductID
+		}
+		return missing[a].Path < missing[b].Path
+	})
+	return missing
+}
+
+// CreateMissing 逐级创建缺失的模块路径（已存在的上级模块直接复用），遇到错误立即返回
+func (r *ModuleResolver) CreateMissing(paths []ModulePath) error {
+	for _, p := range paths {
+		if _, err := r.create(p.ProductID, p.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Resolve 将需求的模块路径解析为模块ID（写入 Story.Module），返回无法解析的路径问题
+// Excel模块列填写路径的行使用该路径；未填写模块且 defaultModule 为路径时使用默认路径
+// skipMissing 为true时（演练中将自动创建缺失模块）不报告不存在的路径
+func (r *ModuleResolver) Resolve(stories []story.Story, defaultPath string, skipMissing bool) []PreflightIssue {
+	var issues []PreflightIssue
+	for idx := range stories {
+		s := &stories[idx]
+		path := modulePathOf(s, defaultPath)
+		if path == "" {
+			continue
+		}
+		tree, err := r.tree(s.ProductID)
+		if err != nil {
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "模块", Message: fmt.Sprintf("无法获取产品 %d 的模块列表: %v", s.ProductID, err)})
+			continue
+		}
+		id, ok := tree[path]
+		if !ok {
+			if skipMissing {
+				continue
+			}
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "模块",
+				Message: fmt.Sprintf("模块路径 %s 在产品 %d 中不存在（可使用 -create-modules 自动创建）", path, s.ProductID)})
+			continue
+		}
+		r.logger.Debug("行%d 模块路径 %s 解析为模块ID %d", s.RowIndex, path, id)
+		s.Module = id
+	}
+	return issues
+}
+
+// modulePathOf 返回需求需要解析的模块路径（Excel填写了模块ID时为空）
+func modulePathOf(s *story.Story, defaultPath string) string {
+	if s.ModulePath != "" {
+		return s.ModulePath
+	}
+	if s.Module < 0 {
+		return story.NormalizeModulePath(defaultPath)
+	}
+	return ""
+}
+
+// tree 返回产品的模块路径索引（按产品缓存，查询失败时同样缓存错误）
+func (r *ModuleResolver) tree(productID int) (map[string]int, error) {
+	if err, failed := r.errs[productID]; failed {
+		return nil, err
+	}
+	if tree, ok := r.trees[productID]; ok {
+		return tree, nil
+	}
+	modules, err := r.modules.ListByProduct(productID)
+	if err != nil {
+		r.errs[productID] = err
+		return nil, err
+	}
+	tree := buildModulePaths(modules)
+	r.trees[productID] = tree
+	return tree, nil
+}
+
+// buildModulePaths 根据模块的父子关系计算每个模块的完整路径（各级名称以 "/" 连接）
+func buildModulePaths(modules []Module) map[string]int {
+	byID := make(map[int]Module, len(modules))
+	for _, m := range modules {
+		byID[m.ID] = m
+	}
+	paths := make(map[string]int, len(modules))
+	for _, m := range modules {
+		path := modulePath(m, byID)
+		if _, dup := paths[path]; !dup {
+			paths[path] = m.ID
+		}
+	}
+	return paths
+}
+
+// modulePath 沿上级模块计算模块的完整路径（各级名称以 "/" 连接）
+func modulePath(m Module, byID map[int]Module) string {
+	segments := []string{strings.TrimSpace(m.Name)}
+	visited := map[int]bool{m.ID: true}
+	for parent, ok := byID[m.Parent]; ok && !visited[parent.ID]; parent, ok = byID[parent.Parent] {
+		visited[parent.ID] = true
+		segments = append([]string{strings.TrimSpace(parent.Name)}, segments...)
+	}
+	return strings.Join(segments, "/")
+}
+
+// create 逐级创建模块路径，返回末级模块ID
+func (r *ModuleResolver) create(productID int, path string) (int, error) {
+	tree, err := r.tree(productID)
+	if err != nil {
+		return 0, fmt.Errorf("无法获取产品 %d 的模块列表: %v", productID, err)
+	}
+
+	parent := 0
+	var prefix []string
+	for _, name := range strings.Split(path, "/") {
+		prefix = append(prefix, name)
+		current := strings.Join(prefix, "/")
+		if id, ok := tree[current]; ok {
+			parent = id
+			continue
+		}
+
+		resp, rsp, err := r.modules.Create(ModuleCreateRequest{ProductID: productID, Parent: parent, Name: name})
+		if err != nil {
+			return 0, fmt.Errorf("创建模块 %s 失败(产品ID=%d): %v", current, productID, err)
+		}
+		if rsp != nil && rsp.StatusCode >= 400 {
+			return 0, fmt.Errorf("创建模块 %s 失败(产品ID=%d)，HTTP状态码: %d", current, productID, rsp.StatusCode)
+		}
+		if resp == nil || resp.Status != "success" {
+			return 0, fmt.Errorf("创建模块 %s 失败(产品ID=%d)，禅道返回失败状态", current, productID)
+		}
+
+		id := resp.ID
+		if id == 0 {
+			// 部分禅道版本创建模块不返回ID，重新查询模块树
+			delete(r.trees, productID)
+			if tree, err = r.tree(productID); err != nil {
+				return 0, fmt.Errorf("创建模块 %s 后无法获取产品 %d 的模块列表: %v", current, productID, err)
+			}
+			if id = tree[current]; id == 0 {
+				return 0, fmt.Errorf("创建模块 %s 后未在产品 %d 的模块列表中找到", current, productID)
+			}
+		}
+		tree[current] = id
+		parent = id
+		r.logger.Info("已创建模块 %s (产品ID=%d, 模块ID=%d)", current, productID, id)
+	}
+	return parent, nil
+}
@@ -0,0 +1,108 @@
+package zentao
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// moduleTree 测试用产品模块树：支付(10) / 退款(11) / 对账(12)，以及顶级模块 会员(20)
+func moduleTree() []Module {
+	return []Module{
+		{ID: 10, Name: "支付", Parent: 0},
+		{ID: 11, Name: "退款", Parent: 10},
+		{ID: 12, Name: "对账", Parent: 11},
+		{ID: 20, Name: "会员", Parent: 0},
+	}
+}
+
+func TestModuleResolver_Resolve(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	mockModule := &mockModuleService{
+		listFn: func(productID int) ([]Module, error) { return moduleTree(), nil },
+	}
+	resolver := NewModuleResolverWithMocks(log, mockModule)
+
+	stories := []story.Story{
+		{RowIndex: 1, ProductID: 1, Module: -1, ModulePath: "支付/退款/对账"},
+		{RowIndex: 2, ProductID: 1, Module: -1},
+		{RowIndex: 3, ProductID: 1, Module: 5},
+		{RowIndex: 4, ProductID: 1, Module: -1, ModulePath: "支付/不存在"},
+	}
+	issues := resolver.Resolve(stories, "会员", false)
+
+	if stories[0].Module != 12 {
+		t.Errorf("行1 模块ID = %d, want 12", stories[0].Module)
+	}
+	if stories[1].Module != 20 {
+		t.Errorf("行2 未填写模块时应使用默认模块路径, 模块ID = %d, want 20", stories[1].Module)
+	}
+	if stories[2].Module != 5 {
+		t.Errorf("行3 填写了模块ID不应改变, 模块ID = %d, want 5", stories[2].Module)
+	}
+	if len(issues) != 1 || issues[0].RowIndex != 4 || !strings.Contains(issues[0].Message, "支付/不存在") {
+		t.Fatalf("期望行4报告模块路径不存在, 得到 %v", issues)
+	}
+
+	if issues := resolver.Resolve(stories[3:], "", true); len(issues) != 0 {
+		t.Errorf("skipMissing 时不应报告不存在的路径, 得到 %v", issues)
+	}
+}
+
+func TestModuleResolver_CreateMissing(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	modules := moduleTree()
+	var created []ModuleCreateRequest
+	mockModule := &mockModuleService{
+		listFn: func(productID int) ([]Module, error) { return modules, nil },
+		createFn: func(r ModuleCreateRequest) (*ModuleCreateResponse, *req.Response, error) {
+			created = append(created, r)
+			id := 100 + len(created)
+			modules = append(modules, Module{ID: id, Name: r.Name, Parent: r.Parent})
+			if r.Name == "差错" {
+				id = 0 // 模拟不返回ID的禅道版本
+			}
+			return &ModuleCreateResponse{Status: "success", ID: id}, nil, nil
+		},
+	}
+	resolver := NewModuleResolverWithMocks(log, mockModule)
+
+	stories := []story.Story{
+		{RowIndex: 1, ProductID: 1, Module: -1, ModulePath: "支付/退款/差错/人工"},
+		{RowIndex: 2, ProductID: 1, Module: -1, ModulePath: "支付/退款/差错/人工"},
+		{RowIndex: 3, ProductID: 1, Module: -1, ModulePath: "支付/退款"},
+	}
+
+	missing := resolver.Missing(stories, "")
+	if len(missing) != 1 || missing[0] != (ModulePath{ProductID: 1, Path: "支付/退款/差错/人工"}) {
+		t.Fatalf("Missing() = %v, want [产品1: 支付/退款/差错/人工]", missing)
+	}
+
+	if err := resolver.CreateMissing(missing); err != nil {
+		t.Fatalf("CreateMissing() error = %v", err)
+	}
+	if len(created) != 2 {
+		t.Fatalf("应只创建缺失的2级模块, 实际创建 %d 个: %+v", len(created), created)
+	}
+	if created[0].Name != "差错" || created[0].Parent != 11 || created[0].ProductID != 1 {
+		t.Errorf("第1个创建请求 = %+v, want 差错(父模块11)", created[0])
+	}
+	if created[1].Name != "人工" || created[1].Parent != 101 {
+		t.Errorf("第2个创建请求 = %+v, want 人工(父模块101，重新查询得到)", created[1])
+	}
+
+	if issues := resolver.Resolve(stories, "", false); len(issues) != 0 {
+		t.Fatalf("创建后仍有问题: %v", issues)
+	}
+	if stories[0].Module != 102 || stories[1].Module != 102 || stories[2].Module != 11 {
+		t.Errorf("模块ID = %d/%d/%d, want 102/102/11", stories[0].Module, stories[1].Module, stories[2].Module)
+	}
+}
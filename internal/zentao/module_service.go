@@ -0,0 +1,103 @@
+// Package zentao 封装禅道API客户端 - Module模块服务
+package zentao
+
+import (
+	"fmt"
+
+	"github.com/imroc/req/v3"
+)
+
+// Module 模块信息（禅道返回树形结构，Children为子模块）
+type Module struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Parent   int      `json:"parent"`
+	Path     string   `json:"path"`
+	Grade    int      `json:"grade"`
+	Children []Module `json:"children,omitempty"`
+}
+
+// ModuleTreeResponse 模块树响应
+type ModuleTreeResponse struct {
+	Status  string   `json:"status"`
+	Modules []Module `json:"modules"`
+}
+
+// ModuleCreateRequest 创建需求模块的请求体
+type ModuleCreateRequest struct {
+	ProductID int    `json:"-"`      // 产品ID（路径参数）
+	Parent    int    `json:"parent"` // 父模块ID，0表示顶级模块
+	Name      string `json:"name"`   // 模块名称
+	Type      string `json:"type"`   // 模块类型，需求模块为 story
+}
+
+// ModuleCreateResponse 创建需求模块的响应
+type ModuleCreateResponse struct {
+	Status string `json:"status"` // 状态(success 成功 | fail 失败)
+	ID     int    `json:"id"`     // 创建的模块ID（部分版本不返回）
+}
+
+// ModuleService 模块服务
+type ModuleService struct {
+	client *Client
+}
+
+// NewModuleService 创建新的模块服务
+func NewModuleService(client *Client) *ModuleService {
+	return &ModuleService{client: client}
+}
+
+// ProductModules 获取产品需求模块树
+// GET /api.php/v2/products/{id}/modules?type=story
+func (s *ModuleService) ProductModules(productID int) (*ModuleTreeResponse, *req.Response, error) {
+	var resp ModuleTreeResponse
+	rsp, err := s.client.R().
+		SetQueryParam("type", "story").
+		SetSuccessResult(&resp).
+		Get(s.client.RequestURL(fmt.Sprintf("/products/%d/modules", productID)))
+	if err != nil {
+		return nil, rsp, err
+	}
+	return &resp, rsp, nil
+}
+
+// Create 在产品下创建需求模块
+// POST /api.php/v2/products/{id}/modules
+func (s *ModuleService) Create(req ModuleCreateRequest) (*ModuleCreateResponse, *req.Response, error) {
+	var resp ModuleCreateResponse
+	if req.Type == "" {
+		req.Type = "story"
+	}
+	rsp, err := s.client.R().
+		SetBody(&req).
+		SetSuccessResult(&resp).
+		Post(s.client.RequestURL(fmt.Sprintf("/products/%d/modules", req.ProductID)))
+	if err != nil {
+		return nil, rsp, err
+	}
+	return &resp, rsp, nil
+}
+
+// ListByProduct 获取产品所有需求模块（树形结构展开为列表）
+func (s *ModuleService) ListByProduct(productID int) ([]Module, error) {
+	resp, rsp, err := s.ProductModules(productID)
+	if err != nil {
+		return nil, fmt.Errorf("获取产品模块失败: %w", err)
+	}
+	if rsp != nil && rsp.StatusCode >= 400 {
+		return nil, fmt.Errorf("获取产品模块失败，HTTP状态码: %d", rsp.StatusCode)
+	}
+	return flattenModules(resp.Modules), nil
+}
+
+// flattenModules 将模块树按先序遍历展开为列表
+func flattenModules(tree []Module) []Module {
+	var modules []Module
+	for _, m := range tree {
+		children := m.Children
+		m.Children = nil
+		modules = append(modules, m)
+		modules = append(modules, flattenModules(children)...)
+	}
+	return modules
+}
@@ -0,0 +1,165 @@
+// Package zentao 封装禅道API客户端 - 父需求导入失败时的处理策略
+package zentao
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// ParentFailurePolicy 父需求导入失败时子需求的处理策略
+type ParentFailurePolicy string
+
+const (
+	ParentFailureOrphan ParentFailurePolicy = "orphan" // 仍然创建子需求，但不设置父需求（原有行为）
+	ParentFailureSkip   ParentFailurePolicy = "skip"   // 跳过失败行的全部后代需求
+	ParentFailureAbort  ParentFailurePolicy = "abort"  // 被引用为父需求的行失败时中止导入，剩余行全部跳过
+)
+
+// ParseParentFailurePolicy 解析父需求失败策略，空字符串返回默认策略 orphan
+func ParseParentFailurePolicy(s string) (ParentFailurePolicy, error) {
+	switch p := ParentFailurePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return ParentFailureOrphan, nil
+	case ParentFailureOrphan, ParentFailureSkip, ParentFailureAbort:
+		return p, nil
+	default:
+		return "", fmt.Errorf("无效的父需求失败策略: %s，支持: skip/orphan/abort", s)
+	}
+}
+
+// SetParentFailurePolicy 设置父需求导入失败时子需求的处理策略（默认 orphan）
+func (i *Importer) SetParentFailurePolicy(p ParentFailurePolicy) {
+	i.parentPolicy = p
+}
+
+// importRun 一次 ImportStories 调用的运行状态（并发导入时 rowIDMap、failedRoot、abortReason 由 mu 保护）
+type importRun struct {
+	mu          sync.Mutex
+	stories     []story.Story
+	results     []ImportResult
+	rowIDMap    map[int]int  // 行号 -> 禅道ID（用于解析 @n 引用）
+	rowIdx      map[int]int  // 行号 -> stories 下标
+	referenced  map[int]bool // 被其他行以 @n 引用的行号
+	failedRoot  map[int]int  // 被跳过的行号 -> 导致跳过的失败行号
+	abortReason string       // 非空表示导入已中止
+}
+
+// newImportRun 创建运行状态并预先计算行号索引和被引用的行
+func newImportRun(stories []story.Story) *importRun {
+	run := &importRun{
+		stories:    stories,
+		results:    make([]ImportResult, len(stories)),
+		rowIDMap:   make(map[int]int),
+		rowIdx:     make(map[int]int, len(stories)),
+		referenced: make(map[int]bool),
+		failedRoot: make(map[int]int),
+	}
+	for idx, s := range stories {
+		run.rowIdx[s.RowIndex] = idx
+		if row, isRowRef, err := story.ParseRowRef(s.ParentRef); isRowRef && err == nil {
+			run.referenced[row] = true
+		}
+	}
+	return run
+}
+
+// rowID 返回行号对应的已导入禅道ID
+func (run *importRun) rowID(row int) (int, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	id, ok := run.rowIDMap[row]
+	return id, ok
+}
+
+// setRowID 记录行号对应的禅道ID
+func (run *importRun) setRowID(row, id int) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.rowIDMap[row] = id
+}
+
+// aborted 返回导入中止原因，未中止时为空
+func (run *importRun) aborted() string {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.abortReason
+}
+
+// failedParent 返回 "@n" 父需求未能导入时导致失败的根源行号（父需求本身失败时即为父需求行号）
+func (run *importRun) failedParent(s *story.Story) (int, bool) {
+	row, isRowRef, err := story.ParseRowRef(s.ParentRef)
+	if !isRowRef || err != nil {
+		return 0, false
+	}
+	idx, ok := run.rowIdx[row]
+	if !ok {
+		return 0, false
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if _, created := run.rowIDMap[row]; created {
+		return 0, false
+	}
+	parent := run.results[idx]
+	if parent.Skipped {
+		if root, ok := run.failedRoot[row]; ok {
+			return root, true
+		}
+		return row, true
+	}
+	if parent.Success || parent.Error == nil {
+		return 0, false // 父需求尚未导入
+	}
+	return row, true
+}
+
+// skipResult 构造跳过结果
+func skipResult(s *story.Story, reason string) ImportResult {
+	return ImportResult{StoryType: string(s.Type), Skipped: true, SkipReason: reason}
+}
+
+// checkParentFailure 按策略处理父需求导入失败的行，返回 true 表示该行已被跳过
+func (i *Importer) checkParentFailure(run *importRun, idx int) bool {
+	s := &run.stories[idx]
+
+	if reason := run.aborted(); reason != "" {
+		run.results[idx] = skipResult(s, reason)
+		i.recordJournal(s, run.results[idx])
+		return true
+	}
+
+	if i.parentPolicy != ParentFailureSkip {
+		return false
+	}
+	root, failed := run.failedParent(s)
+	if !failed {
+		return false
+	}
+	reason := fmt.Sprintf("父需求 %s 未能导入（行%d 导入失败），已跳过", s.ParentRef, root)
+	i.logger.Error("行%d %s: %s", s.RowIndex, reason, s.Title)
+	run.mu.Lock()
+	run.failedRoot[s.RowIndex] = root
+	run.mu.Unlock()
+	run.results[idx] = skipResult(s, reason)
+	i.recordJournal(s, run.results[idx])
+	return true
+}
+
+// checkAbort abort策略下，被引用为父需求的行导入失败时中止后续导入
+func (i *Importer) checkAbort(run *importRun, idx int) {
+	s := &run.stories[idx]
+	result := run.results[idx]
+	if i.parentPolicy != ParentFailureAbort || result.Success || result.Skipped || !run.referenced[s.RowIndex] {
+		return
+	}
+	reason := fmt.Sprintf("行%d 导入失败且被其他行引用为父需求，导入已中止", s.RowIndex)
+	run.mu.Lock()
+	if run.abortReason == "" {
+		run.abortReason = reason
+	}
+	run.mu.Unlock()
+	i.logger.Error("%s", reason)
+}
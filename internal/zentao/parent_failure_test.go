@@ -0,0 +1,121 @@
+package zentao
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestImporter_ImportStories_ParentFailurePolicy(t *testing.T) {
+	tests := []struct {
+		policy      ParentFailurePolicy
+		wantSkipped []bool // 每行是否被跳过
+		wantReqs    int    // Requirement创建次数
+		wantStories int    // Story创建次数
+	}{
+		{ParentFailureOrphan, []bool{false, false, false, false}, 1, 2},
+		{ParentFailureSkip, []bool{false, true, true, false}, 0, 1},
+		{ParentFailureAbort, []bool{false, true, true, true}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			var buf bytes.Buffer
+			log := logger.NewLoggerWithWriter(&buf)
+
+			reqCalls, storyCalls := 0, 0
+			mockEpic := &mockEpicService{
+				createFn: func(req EpicCreateRequest) (*EpicCreateResponse, *req.Response, error) {
+					return nil, nil, fmt.Errorf("服务器错误")
+				},
+				listFn: func(productID int) ([]EpicListItem, error) {
+					return nil, nil
+				},
+			}
+			mockReq := &mockReqService{
+				createFn: func(req RequirementCreateRequest) (*RequirementCreateResponse, *req.Response, error) {
+					reqCalls++
+					if req.Parent != 0 {
+						t.Errorf("父需求失败时Requirement不应有父需求, 得到 %d", req.Parent)
+					}
+					return &RequirementCreateResponse{Status: "success"}, nil, nil
+				},
+				listFn: func(productID int) ([]RequirementListItem, error) {
+					if reqCalls == 0 {
+						return nil, nil
+					}
+					return []RequirementListItem{{ID: 601, Title: "R"}}, nil
+				},
+			}
+			mockStorySvc := &mockStoryService{
+				createFn: func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+					storyCalls++
+					return &StoryCreateResponse{Status: "success", ID: 700 + storyCalls}, nil, nil
+				},
+				listFn: func(productID int) ([]StoryListItem, error) {
+					return nil, nil
+				},
+			}
+
+			importer := NewImporterWithMocks(log, mockEpic, mockReq, mockStorySvc, &mockConfig{reviewer: "tester"})
+			importer.SetParentFailurePolicy(tt.policy)
+
+			results := importer.ImportStories([]story.Story{
+				{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, RowIndex: 1},
+				{Type: story.StoryTypeRequirement, Title: "R", ProductID: 1, ParentRef: "@1", RowIndex: 2},
+				{Type: story.StoryTypeStory, Title: "S", ProductID: 1, ParentRef: "@2", RowIndex: 3},
+				{Type: story.StoryTypeStory, Title: "T", ProductID: 1, RowIndex: 4},
+			})
+
+			if results[0].Success || results[0].Skipped {
+				t.Errorf("Epic应导入失败而不是跳过: %+v", results[0])
+			}
+			for idx, want := range tt.wantSkipped {
+				if results[idx].Skipped != want {
+					t.Errorf("行%d Skipped = %v, want %v (%+v)", idx+1, results[idx].Skipped, want, results[idx])
+				}
+				if want && !strings.Contains(results[idx].SkipReason, "行1") {
+					t.Errorf("行%d 跳过原因应指向失败的行1, 得到 %q", idx+1, results[idx].SkipReason)
+				}
+			}
+			if reqCalls != tt.wantReqs || storyCalls != tt.wantStories {
+				t.Errorf("创建次数 Requirement=%d Story=%d, want %d %d", reqCalls, storyCalls, tt.wantReqs, tt.wantStories)
+			}
+
+			report := importer.GenerateReport(results)
+			if skipped := countSkipped(tt.wantSkipped); skipped > 0 && !strings.Contains(report, fmt.Sprintf("跳过数量: %d", skipped)) {
+				t.Errorf("报告应显示跳过数量 %d:\n%s", skipped, report)
+			}
+			if !strings.Contains(report, "失败数量: 1") {
+				t.Errorf("报告中失败数量应为1（跳过的行不计入失败）:\n%s", report)
+			}
+		})
+	}
+}
+
+func countSkipped(skipped []bool) int {
+	count := 0
+	for _, s := range skipped {
+		if s {
+			count++
+		}
+	}
+	return count
+}
+
+func TestParseParentFailurePolicy(t *testing.T) {
+	if p, err := ParseParentFailurePolicy(""); err != nil || p != ParentFailureOrphan {
+		t.Errorf("空值应为默认策略orphan, 得到 %q %v", p, err)
+	}
+	if p, err := ParseParentFailurePolicy(" Skip "); err != nil || p != ParentFailureSkip {
+		t.Errorf("应解析为skip, 得到 %q %v", p, err)
+	}
+	if _, err := ParseParentFailurePolicy("ignore"); err == nil {
+		t.Error("无效策略应返回错误")
+	}
+}
@@ -0,0 +1,110 @@
+// Package zentao 封装禅道API客户端 - 按标题引用产品中已有的父需求
+package zentao
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// ParentTitleResolver 将 "#title:标题" 父需求引用解析为产品中已有的业务需求或用户需求的禅道ID
+type ParentTitleResolver struct {
+	logger   *logger.Logger
+	epicSvc  EpicCreator
+	reqSvc   RequirementCreator
+	storySvc StoryCreator
+	items    map[int][]ProductItem // 产品ID -> 已有的业务需求和用户需求
+	errs     map[int]error         // 产品ID -> 需求列表查询错误
+}
+
+// NewParentTitleResolver 创建新的父需求标题解析器
+func NewParentTitleResolver(client *Client, log *logger.Logger) *ParentTitleResolver {
+	return NewParentTitleResolverWithMocks(log, client.Epic, client.Requirement, client.Story)
+}
+
+// NewParentTitleResolverWithMocks 创建父需求标题解析器（用于测试，直接注入mock实现）
+// 研发需求列表仅用于需求分层去重（见 listProductItems），研发需求不会作为匹配结果
+func NewParentTitleResolverWithMocks(log *logger.Logger, epicSvc EpicCreator, reqSvc RequirementCreator, storySvc StoryCreator) *ParentTitleResolver {
+	return &ParentTitleResolver{
+		logger:   log,
+		epicSvc:  epicSvc,
+		reqSvc:   reqSvc,
+		storySvc: storySvc,
+		items:    make(map[int][]ProductItem),
+		errs:     make(map[int]error),
+	}
+}
+
+// Resolve 在需求所属产品已有的业务需求和用户需求中按标题查找 "#title:标题" 引用的父需求，结果写入 Story.ParentID
+// 只匹配类型层级不低于本行的需求（如业务需求只能以业务需求为父需求），没有匹配或匹配到多个需求时报告问题
+func (r *ParentTitleResolver) Resolve(stories []story.Story) []PreflightIssue {
+	var issues []PreflightIssue
+	for idx := range stories {
+		s := &stories[idx]
+		if s.ParentTitle == "" {
+			continue
+		}
+		if err := r.resolve(s); err != nil {
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "父需求", Message: err.Error()})
+		}
+	}
+	return issues
+}
+
+// resolve 解析单行的父需求标题
+func (r *ParentTitleResolver) resolve(s *story.Story) error {
+	items, err := r.list(s.ProductID)
+	if err != nil {
+		return err
+	}
+	var matches []ProductItem
+	for _, item := range items {
+		if strings.TrimSpace(item.Title) == s.ParentTitle && item.Type.Level() <= s.Type.Level() {
+			matches = append(matches, item)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("父需求 %s 在产品 %d 的业务需求和用户需求中不存在（父需求的类型层级不能低于%s）",
+			s.ParentRef, s.ProductID, s.GetTypeString())
+	case 1:
+	default:
+		names := make([]string, len(matches))
+		for n, item := range matches {
+			t := story.Story{Type: item.Type}
+			names[n] = fmt.Sprintf("%d %s", item.ID, t.GetTypeString())
+		}
+		return fmt.Errorf("父需求 %s 对应多个需求（%s），请改为填写禅道ID", s.ParentRef, strings.Join(sortedStrings(names), "、"))
+	}
+
+	s.ParentID = matches[0].ID
+	r.logger.Debug("行%d 父需求 %s 解析为禅道ID %d", s.RowIndex, s.ParentRef, s.ParentID)
+	return nil
+}
+
+// list 返回产品已有的业务需求和用户需求（按产品缓存，查询失败时同样缓存错误）
+func (r *ParentTitleResolver) list(productID int) ([]ProductItem, error) {
+	if err, failed := r.errs[productID]; failed {
+		return nil, err
+	}
+	if items, ok := r.items[productID]; ok {
+		return items, nil
+	}
+
+	all, errs := listProductItems(productID, r.epicSvc, r.reqSvc, r.storySvc)
+	if len(errs) > 0 {
+		r.errs[productID] = fmt.Errorf("无法获取产品 %d 的需求列表: %v", productID, errs[0])
+		return nil, r.errs[productID]
+	}
+	var items []ProductItem
+	for _, item := range all {
+		if item.Type == story.StoryTypeEpic || item.Type == story.StoryTypeRequirement {
+			items = append(items, item)
+		}
+	}
+	r.items[productID] = items
+	return items, nil
+}
@@ -0,0 +1,95 @@
+package zentao
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestParentTitleResolver_Resolve(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	// 产品1: 业务需求 10(会员体系)，用户需求 20(积分)、21(会员体系)，研发需求 30(积分)
+	// Epic API 同时返回关联的用户需求和研发需求，需按类型去重
+	epics := &mockEpicService{listFn: func(productID int) ([]EpicListItem, error) {
+		if productID != 1 {
+			return nil, errors.New("产品不存在")
+		}
+		return []EpicListItem{{ID: 10, Title: "会员体系"}, {ID: 20, Title: "积分"}, {ID: 30, Title: "积分"}}, nil
+	}}
+	reqs := &mockReqService{listFn: func(productID int) ([]RequirementListItem, error) {
+		return []RequirementListItem{{ID: 20, Title: "积分"}, {ID: 21, Title: "会员体系"}, {ID: 30, Title: "积分"}}, nil
+	}}
+	stories := &mockStoryService{listFn: func(productID int) ([]StoryListItem, error) {
+		return []StoryListItem{{ID: 30, Title: "积分"}}, nil
+	}}
+	resolver := NewParentTitleResolverWithMocks(log, epics, reqs, stories)
+
+	rows := []story.Story{
+		{RowIndex: 1, Type: story.StoryTypeStory, ProductID: 1, ParentRef: "#title:积分", ParentTitle: "积分"},
+		{RowIndex: 2, Type: story.StoryTypeEpic, ProductID: 1, ParentRef: "#title:会员体系", ParentTitle: "会员体系"},
+		{RowIndex: 3, Type: story.StoryTypeStory, ProductID: 1, ParentRef: "#title:会员体系", ParentTitle: "会员体系"},
+		{RowIndex: 4, Type: story.StoryTypeRequirement, ProductID: 1, ParentRef: "#title:不存在", ParentTitle: "不存在"},
+		{RowIndex: 5, Type: story.StoryTypeEpic, ProductID: 1, ParentRef: "#title:积分", ParentTitle: "积分"},
+		{RowIndex: 6, Type: story.StoryTypeStory, ProductID: 2, ParentRef: "#title:积分", ParentTitle: "积分"},
+		{RowIndex: 7, Type: story.StoryTypeStory, ProductID: 1, ParentRef: "@1"},
+	}
+	issues := resolver.Resolve(rows)
+
+	if rows[0].ParentID != 20 {
+		t.Errorf("行1 应匹配用户需求20（研发需求30不参与匹配）, 得到 %d", rows[0].ParentID)
+	}
+	if rows[1].ParentID != 10 {
+		t.Errorf("行2 业务需求只能以业务需求10为父需求, 得到 %d", rows[1].ParentID)
+	}
+
+	want := map[int]string{
+		3: "对应多个需求（10 业务需求(Epic)、21 用户需求(Requirement)）",
+		4: "在产品 1 的业务需求和用户需求中不存在",
+		5: "在产品 1 的业务需求和用户需求中不存在",
+		6: "无法获取产品 2 的需求列表",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("期望 %d 个问题, 得到 %d: %v", len(want), len(issues), issues)
+	}
+	for _, issue := range issues {
+		if w, ok := want[issue.RowIndex]; !ok || !strings.Contains(issue.Message, w) {
+			t.Errorf("行%d 问题 = %q, want 包含 %q", issue.RowIndex, issue.Message, w)
+		}
+	}
+	if rows[6].ParentID != 0 {
+		t.Errorf("行7 @行号引用不应被解析, 得到 %d", rows[6].ParentID)
+	}
+}
+
+func TestImporter_ImportStories_UnresolvedParentTitle(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	storyCalls := 0
+	mockStorySvc := &mockStoryService{
+		createFn: func(r StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			storyCalls++
+			return &StoryCreateResponse{Status: "success", ID: 700 + storyCalls}, nil, nil
+		},
+	}
+
+	// 未经 ParentTitleResolver 解析的 "#title:" 引用不能创建为没有父需求的需求
+	importer := NewImporterWithMocks(log, &mockEpicService{}, &mockReqService{}, mockStorySvc, &mockConfig{reviewer: "tester"})
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeStory, Title: "积分兑换", ProductID: 1, ParentRef: "#title:会员体系", ParentTitle: "会员体系", RowIndex: 1},
+	})
+
+	if results[0].Success || results[0].Error == nil {
+		t.Errorf("未解析的父需求标题应导致该行失败, 得到 %+v", results[0])
+	}
+	if storyCalls != 0 {
+		t.Errorf("未解析父需求时不应创建需求, 创建次数 = %d", storyCalls)
+	}
+}
@@ -0,0 +1,190 @@
+// Package zentao 封装禅道API客户端 - 导入前预检
+package zentao
+
+import (
+	"fmt"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// PreflightIssue 表示预检发现的问题
+type PreflightIssue struct {
+	RowIndex int    // 数据行号（0表示非行级问题，如配置项）
+	Field    string // 相关字段
+	Message  string // 问题描述
+}
+
+// String 返回问题的可读描述
+func (p PreflightIssue) String() string {
+	if p.RowIndex == 0 {
+		return fmt.Sprintf("[%s] %s", p.Field, p.Message)
+	}
+	return fmt.Sprintf("行%d [%s] %s", p.RowIndex, p.Field, p.Message)
+}
+
+// Preflight 导入前通过禅道API校验数据：产品、模块、评审人和指派人是否存在，父需求引用是否可解析
+type Preflight struct {
+	logger   *logger.Logger
+	products ProductGetter
+	modules  ModuleLister
+	users    UserLister
+	config   ConfigProvider
+
+	productCache map[int]error        // 产品ID -> 查询错误（nil表示存在）
+	moduleCache  map[int]map[int]bool // 产品ID -> 模块ID集合
+	moduleErrs   map[int]error        // 产品ID -> 模块查询错误
+	userIndex    *UserIndex           // 用户索引（nil表示尚未加载）
+	usersErr     error
+}
+
+// NewPreflight 创建新的预检器
+func NewPreflight(client *Client, log *logger.Logger) *Preflight {
+	return NewPreflightWithMocks(log, client.Product, client.Module, client.User, client.config)
+}
+
+// NewPreflightWithMocks 创建预检器（用于测试，直接注入mock实现）
+func NewPreflightWithMocks(log *logger.Logger, products ProductGetter, modules ModuleLister, users UserLister, cfg ConfigProvider) *Preflight {
+	return &Preflight{
+		logger:       log,
+		products:     products,
+		modules:      modules,
+		users:        users,
+		config:       cfg,
+		productCache: make(map[int]error),
+		moduleCache:  make(map[int]map[int]bool),
+		moduleErrs:   make(map[int]error),
+	}
+}
+
+// Check 校验所有需求，返回发现的全部问题（不会中途退出）
+func (p *Preflight) Check(stories []story.Story) []PreflightIssue {
+	var issues []PreflightIssue
+
+	issues = append(issues, p.CheckAccounts(stories)...)
+
+	for _, s := range stories {
+		if err := p.checkProduct(s.ProductID); err != nil {
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "产品ID", Message: err.Error()})
+			continue
+		}
+
+		// 与Importer.resolveModule相同的回退规则：Excel未填写(-1)时使用配置默认值
+		moduleID := s.Module
+		if moduleID < 0 {
+			moduleID = p.config.GetDefaultModule()
+		}
+		if moduleID > 0 {
+			if err := p.checkModule(s.ProductID, moduleID); err != nil {
+				issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "模块ID", Message: err.Error()})
+			}
+		}
+	}
+
+	// "@行号" 父需求引用：行不存在、引用自身、成环和类型层级错误
+	for _, issue := range story.ValidateReferences(stories) {
+		issues = append(issues, PreflightIssue{RowIndex: issue.RowIndex, Field: "父需求ID", Message: issue.Message})
+	}
+	// 同类型子需求的层级不能超过产品允许的最大层级
+	for _, issue := range story.ValidateGrades(stories, p.config.GetMaxGrade) {
+		issues = append(issues, PreflightIssue{RowIndex: issue.RowIndex, Field: "父需求ID", Message: issue.Message})
+	}
+
+	p.logger.Info("预检完成，共 %d 个需求，发现 %d 个问题", len(stories), len(issues))
+	return issues
+}
+
+// CheckAccounts 校验配置的默认评审人/指派人以及Excel"指派给""评审人"列中的账号是否存在（用户列表只加载一次）
+// 可以填写账号或姓名，姓名不存在或对应多个账号（重名）时报告问题
+func (p *Preflight) CheckAccounts(stories []story.Story) []PreflightIssue {
+	var issues []PreflightIssue
+
+	if reviewer := p.config.GetDefaultReviewer(); reviewer != "" {
+		if err := p.checkAccount(reviewer); err != nil {
+			issues = append(issues, PreflightIssue{Field: "defaultReviewer", Message: err.Error()})
+		}
+	}
+	if assignee := p.config.GetDefaultAssignedTo(); assignee != "" {
+		if err := p.checkAccount(assignee); err != nil {
+			issues = append(issues, PreflightIssue{Field: "defaultAssignedTo", Message: err.Error()})
+		}
+	}
+
+	for _, s := range stories {
+		if s.AssignedTo != "" {
+			if err := p.checkAccount(s.AssignedTo); err != nil {
+				issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "指派给", Message: err.Error()})
+			}
+		}
+		for _, reviewer := range s.Reviewers {
+			if err := p.checkAccount(reviewer); err != nil {
+				issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "评审人", Message: err.Error()})
+			}
+		}
+	}
+	return issues
+}
+
+// checkProduct 检查产品是否存在（结果按产品缓存）
+func (p *Preflight) checkProduct(productID int) error {
+	if err, ok := p.productCache[productID]; ok {
+		return err
+	}
+	var err error
+	if _, getErr := p.products.GetByID(productID); getErr != nil {
+		err = fmt.Errorf("产品 %d 不存在或无权限: %v", productID, getErr)
+	}
+	p.productCache[productID] = err
+	return err
+}
+
+// checkModule 检查模块是否属于该产品（模块列表按产品缓存）
+func (p *Preflight) checkModule(productID, moduleID int) error {
+	if _, ok := p.moduleCache[productID]; !ok {
+		if _, failed := p.moduleErrs[productID]; !failed {
+			modules, err := p.modules.ListByProduct(productID)
+			if err != nil {
+				p.moduleErrs[productID] = err
+			} else {
+				ids := make(map[int]bool, len(modules))
+				for _, m := range modules {
+					ids[m.ID] = true
+				}
+				p.moduleCache[productID] = ids
+			}
+		}
+	}
+	if err := p.moduleErrs[productID]; err != nil {
+		return fmt.Errorf("无法获取产品 %d 的模块列表: %v", productID, err)
+	}
+	if !p.moduleCache[productID][moduleID] {
+		return fmt.Errorf("模块 %d 不存在于产品 %d 中", moduleID, productID)
+	}
+	return nil
+}
+
+// UserIndex 返回用户索引（首次调用时加载用户列表），用于导入时将姓名解析为账号
+func (p *Preflight) UserIndex() (*UserIndex, error) {
+	if p.userIndex == nil && p.usersErr == nil {
+		users, err := p.users.ListAll()
+		if err != nil {
+			p.usersErr = err
+		} else {
+			p.userIndex = NewUserIndex(users)
+		}
+	}
+	if p.usersErr != nil {
+		return nil, fmt.Errorf("无法获取用户列表: %v", p.usersErr)
+	}
+	return p.userIndex, nil
+}
+
+// checkAccount 检查账号或姓名能否唯一解析为未删除的账号
+func (p *Preflight) checkAccount(name string) error {
+	index, err := p.UserIndex()
+	if err != nil {
+		return err
+	}
+	_, err = index.Resolve(name)
+	return err
+}
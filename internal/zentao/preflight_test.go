@@ -0,0 +1,143 @@
+package zentao
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func newTestPreflight(cfg *mockConfig) *Preflight {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	products := &mockProductService{
+		getFn: func(id int) (*Product, error) {
+			if id == 1 {
+				return &Product{ID: 1, Name: "产品A"}, nil
+			}
+			return nil, fmt.Errorf("HTTP 404")
+		},
+	}
+	modules := &mockModuleService{
+		listFn: func(productID int) ([]Module, error) {
+			return []Module{{ID: 5, Name: "支付"}, {ID: 6, Name: "退款", Parent: 5}}, nil
+		},
+	}
+	users := &mockUserService{
+		listFn: func() ([]User, error) {
+			return []User{
+				{Account: "tester", Realname: "测试"},
+				{Account: "gone", Deleted: "1"},
+				{Account: "zhangsan", Realname: "张三"},
+				{Account: "zhangsan2", Realname: "张三"},
+			}, nil
+		},
+	}
+	return NewPreflightWithMocks(log, products, modules, users, cfg)
+}
+
+func TestPreflight_Check_AllValid(t *testing.T) {
+	p := newTestPreflight(&mockConfig{module: 5, reviewer: "tester"})
+
+	stories := []story.Story{
+		{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, Module: -1, RowIndex: 1},
+		{Type: story.StoryTypeRequirement, Title: "R", ProductID: 1, Module: 6, ParentRef: "@1", RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "S", ProductID: 1, Module: 0, ParentRef: "@2", RowIndex: 3},
+	}
+
+	if issues := p.Check(stories); len(issues) != 0 {
+		t.Fatalf("期望无问题, 得到 %v", issues)
+	}
+}
+
+func TestPreflight_Check_CollectsAllIssues(t *testing.T) {
+	p := newTestPreflight(&mockConfig{module: 0, reviewer: "gone"})
+
+	stories := []story.Story{
+		{Type: story.StoryTypeStory, Title: "S1", ProductID: 2, Module: -1, RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "S2", ProductID: 1, Module: 99, RowIndex: 2},
+		{Type: story.StoryTypeStory, Title: "S3", ProductID: 1, Module: -1, ParentRef: "@9", RowIndex: 3},
+		{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, Module: -1, ParentRef: "@3", RowIndex: 4},
+	}
+
+	issues := p.Check(stories)
+
+	wantFields := []string{"defaultReviewer", "产品ID", "模块ID", "父需求ID", "父需求ID"}
+	if len(issues) != len(wantFields) {
+		t.Fatalf("期望 %d 个问题, 得到 %d: %v", len(wantFields), len(issues), issues)
+	}
+	for idx, field := range wantFields {
+		if issues[idx].Field != field {
+			t.Errorf("问题 #%d 期望字段 %s, 得到 %s (%s)", idx+1, field, issues[idx].Field, issues[idx])
+		}
+	}
+}
+
+func TestPreflight_CheckAccounts(t *testing.T) {
+	p := newTestPreflight(&mockConfig{reviewer: "tester", assignee: "nobody"})
+
+	issues := p.CheckAccounts([]story.Story{
+		{Type: story.StoryTypeStory, Title: "S1", RowIndex: 1, AssignedTo: "tester", Reviewers: []string{"tester"}},
+		{Type: story.StoryTypeStory, Title: "S2", RowIndex: 2, AssignedTo: "gone", Reviewers: []string{"测试", "ghost"}},
+		{Type: story.StoryTypeStory, Title: "S3", RowIndex: 3, Reviewers: []string{"张三"}},
+	})
+
+	want := []string{
+		"[defaultAssignedTo] 账号或姓名 nobody 不存在或已删除",
+		"行2 [指派给] 账号或姓名 gone 不存在或已删除",
+		"行2 [评审人] 账号或姓名 ghost 不存在或已删除",
+		"行3 [评审人] 姓名 张三 对应多个账号（zhangsan、zhangsan2），请改为填写账号",
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("期望 %d 个问题, 得到 %d: %v", len(want), len(issues), issues)
+	}
+	for idx, w := range want {
+		if issues[idx].String() != w {
+			t.Errorf("问题 #%d = %q, want %q", idx+1, issues[idx], w)
+		}
+	}
+}
+
+func TestImporter_PlanStories(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	importer := NewImporterWithMocks(log, nil, nil, nil, &mockConfig{module: 3, reviewer: "tester"})
+
+	stories := []story.Story{
+		{Type: story.StoryTypeStory, Title: "S", ProductID: 1, Priority: 2, Module: -1, ParentRef: "@2", RowIndex: 1},
+		{Type: story.StoryTypeEpic, Title: "E", ProductID: 1, Priority: 1, Module: 7, RowIndex: 2},
+	}
+
+	plans := importer.PlanStories(stories)
+
+	if len(plans) != 2 {
+		t.Fatalf("期望2个计划, 得到 %d", len(plans))
+	}
+	// Epic 应先于 Story
+	epicReq, ok := plans[0].Payload.(EpicCreateRequest)
+	if !ok {
+		t.Fatalf("第一个计划应为EpicCreateRequest, 得到 %T", plans[0].Payload)
+	}
+	if epicReq.Module != 7 || epicReq.Reviewer[0] != "tester" {
+		t.Errorf("Epic请求字段不正确: %+v", epicReq)
+	}
+	storyReq, ok := plans[1].Payload.(StoryCreateRequest)
+	if !ok {
+		t.Fatalf("第二个计划应为StoryCreateRequest, 得到 %T", plans[1].Payload)
+	}
+	if storyReq.Module != 3 {
+		t.Errorf("Story应回退到默认模块3, 得到 %d", storyReq.Module)
+	}
+	if stories[0].ParentID != 0 {
+		t.Error("PlanStories不应修改传入的stories")
+	}
+
+	out := FormatPlan(plans)
+	if !strings.Contains(out, "\"productID\": 1") || !strings.Contains(out, "@2") {
+		t.Errorf("格式化输出应包含请求体和父需求引用:\n%s", out)
+	}
+}
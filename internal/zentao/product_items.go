@@ -0,0 +1,132 @@
+// Package zentao 封装禅道API客户端 - 产品需求列表（分层去重）
+package zentao
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// ProductItem 产品下的一个需求（已按类型分层去重）
+type ProductItem struct {
+	ID         int
+	Type       story.StoryType
+	Title      string
+	Parent     int
+	Grade      int // 需求层级（同类型父需求链的深度，1为顶级）
+	Module     int
+	Pri        int
+	Category   string
+	Spec       string
+	Verify     string
+	Source     string
+	SourceNote string
+	Keywords   string
+	Estimate   float64
+	Status     string
+	OpenedBy   string
+	OpenedDate string
+}
+
+// listProductItems 获取产品下全部需求并按类型分层去重
+// 去重策略：Epic API会返回关联的Requirement和Story，Requirement API会返回关联的Story
+// 因此先获取Story，再获取Requirement（去除Story中已有的ID），最后获取Epic（去除Story和Requirement中已有的ID）
+// 某一类型获取失败时继续获取其他类型，失败原因通过errs返回
+func listProductItems(productID int, epics EpicCreator, reqs RequirementCreator, stories StoryCreator) (items []ProductItem, errs []error) {
+	seenIDs := make(map[int]bool) // 已处理的ID集合，用于去重
+
+	// 第一步：获取Story列表（最小集合，不含其他类型的关联数据）
+	storyList, err := stories.ProductsListAll(productID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("获取产品研发需求列表失败: %w", err))
+	} else {
+		for _, s := range storyList {
+			seenIDs[s.ID] = true
+			items = append(items, ProductItem{
+				ID: s.ID, Type: story.StoryTypeStory, Title: s.Title, Parent: parseParentID(s.Parent), Grade: s.Grade,
+				Module: s.Module, Pri: s.Pri, Category: s.Category, Spec: s.Spec, Verify: s.Verify,
+				Source: s.Source, SourceNote: s.SourceNote, Keywords: s.Keywords, Estimate: parseEstimate(s.Estimate),
+				Status: s.Status, OpenedBy: s.OpenedBy, OpenedDate: s.OpenedDate,
+			})
+		}
+	}
+
+	// 第二步：获取Requirement列表，去除已在Story中出现的ID
+	reqList, err := reqs.ProductsListAll(productID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("获取产品用户需求列表失败: %w", err))
+	} else {
+		for _, r := range reqList {
+			if seenIDs[r.ID] {
+				continue // 跳过已在Story列表中出现的ID
+			}
+			seenIDs[r.ID] = true
+			items = append(items, ProductItem{
+				ID: r.ID, Type: story.StoryTypeRequirement, Title: r.Title, Parent: parseParentID(r.Parent), Grade: r.Grade,
+				Module: r.Module, Pri: r.Pri, Category: r.Category, Spec: r.Spec, Verify: r.Verify,
+				Source: r.Source, SourceNote: r.SourceNote, Keywords: r.Keywords, Estimate: parseEstimate(r.Estimate),
+				Status: r.Status, OpenedBy: r.OpenedBy, OpenedDate: r.OpenedDate,
+			})
+		}
+	}
+
+	// 第三步：获取Epic列表，去除已在Story或Requirement中出现的ID
+	epicList, err := epics.ProductsListAll(productID)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("获取产品业务需求列表失败: %w", err))
+	} else {
+		for _, e := range epicList {
+			if seenIDs[e.ID] {
+				continue // 跳过已在Story或Requirement列表中出现的ID
+			}
+			items = append(items, ProductItem{
+				ID: e.ID, Type: story.StoryTypeEpic, Title: e.Title, Parent: parseParentID(e.Parent), Grade: e.Grade,
+				Module: e.Module, Pri: e.Pri, Category: e.Category, Spec: e.Spec, Verify: e.Verify,
+				Source: e.Source, SourceNote: e.SourceNote, Keywords: e.Keywords, Estimate: parseEstimate(e.Estimate),
+				Status: e.Status, OpenedBy: e.OpenedBy, OpenedDate: e.OpenedDate,
+			})
+		}
+	}
+
+	return items, errs
+}
+
+// parseParentID 解析列表项中的parent字段（禅道可能返回数字、数字字符串或空值，<=0表示无父需求）
+func parseParentID(v interface{}) int {
+	var id int
+	switch p := v.(type) {
+	case float64:
+		id = int(p)
+	case int:
+		id = p
+	case json.Number:
+		n, _ := p.Int64()
+		id = int(n)
+	case string:
+		id, _ = strconv.Atoi(strings.TrimSpace(p))
+	}
+	if id < 0 {
+		return 0
+	}
+	return id
+}
+
+// parseEstimate 解析列表项中的estimate字段（禅道可能返回数字或数字字符串）
+func parseEstimate(v interface{}) float64 {
+	switch e := v.(type) {
+	case float64:
+		return e
+	case int:
+		return float64(e)
+	case json.Number:
+		f, _ := e.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(e), 64)
+		return f
+	}
+	return 0
+}
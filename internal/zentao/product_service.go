@@ -3,6 +3,11 @@ package zentao
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
 )
 
 // Product 产品信息
@@ -20,6 +25,13 @@ type ProductDetailResponse struct {
 	Product Product  `json:"product"`
 }
 
+// ProductListWithPagerResponse 带分页信息的产品列表响应
+type ProductListWithPagerResponse struct {
+	Status   string    `json:"status"`
+	Products []Product `json:"products"`
+	Pager    Pager     `json:"pager"`
+}
+
 // ProductService 产品服务
 type ProductService struct {
 	client *Client
@@ -69,3 +81,122 @@ func (s *ProductService) GetProductInfo(productIDs []int) (map[int]string, error
 	
 	return result, nil
 }
+
+// List 获取当前用户可访问的产品列表（单页）
+// GET /api.php/v2/products
+func (s *ProductService) List(opts *ListOptions) (*ProductListWithPagerResponse, *req.Response, error) {
+	var resp ProductListWithPagerResponse
+	req := s.client.R().SetSuccessResult(&resp)
+
+	if opts != nil {
+		if opts.RecPerPage > 0 {
+			req.SetQueryParam("recPerPage", fmt.Sprintf("%d", opts.RecPerPage))
+		}
+		if opts.PageID > 0 {
+			req.SetQueryParam("pageID", fmt.Sprintf("%d", opts.PageID))
+		}
+	}
+
+	rsp, err := req.Get(s.client.RequestURL("/products"))
+	if err != nil {
+		return nil, rsp, err
+	}
+	return &resp, rsp, nil
+}
+
+// ListAll 获取当前用户可访问的所有产品（自动分页）
+// GET /api.php/v2/products
+func (s *ProductService) ListAll() ([]Product, error) {
+	var allProducts []Product
+	pageID := 1
+	pageSize := 100
+
+	for {
+		resp, rsp, err := s.List(&ListOptions{
+			PageID:     pageID,
+			RecPerPage: pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取产品列表失败(页%d): %w", pageID, err)
+		}
+		if rsp != nil && rsp.StatusCode >= 400 {
+			return nil, fmt.Errorf("获取产品列表失败，HTTP状态码: %d", rsp.StatusCode)
+		}
+
+		allProducts = append(allProducts, resp.Products...)
+
+		if resp.Pager.PageTotal == 0 || pageID >= resp.Pager.PageTotal {
+			break
+		}
+		pageID++
+	}
+
+	return allProducts, nil
+}
+
+// Index 获取所有产品并建立 名称/代号 索引（用于将Excel中填写的产品名称或代号解析为产品ID）
+func (s *ProductService) Index() (*ProductIndex, error) {
+	products, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	return NewProductIndex(products), nil
+}
+
+// ProductIndex 产品索引：按产品名称或代号查找产品
+type ProductIndex struct {
+	byKey map[string][]Product // 名称或代号 -> 产品列表（重名时有多个）
+}
+
+// NewProductIndex 根据产品列表建立索引
+func NewProductIndex(products []Product) *ProductIndex {
+	idx := &ProductIndex{byKey: make(map[string][]Product)}
+	for _, p := range products {
+		keys := []string{strings.TrimSpace(p.Name), strings.TrimSpace(p.Code)}
+		for n, key := range keys {
+			// 名称与代号相同时只登记一次
+			if key == "" || (n == 1 && key == keys[0]) {
+				continue
+			}
+			idx.byKey[key] = append(idx.byKey[key], p)
+		}
+	}
+	return idx
+}
+
+// Resolve 将产品名称或代号解析为产品，找不到或对应多个产品时返回错误
+func (idx *ProductIndex) Resolve(ref string) (Product, error) {
+	ref = strings.TrimSpace(ref)
+	switch products := idx.byKey[ref]; len(products) {
+	case 0:
+		return Product{}, fmt.Errorf("产品名称或代号 %s 不存在或无权限", ref)
+	case 1:
+		return products[0], nil
+	default:
+		sorted := append([]Product(nil), products...)
+		sort.Slice(sorted, func(a, b int) bool { return sorted[a].ID < sorted[b].ID })
+		names := make([]string, len(sorted))
+		for n, p := range sorted {
+			names[n] = fmt.Sprintf("%d %s", p.ID, p.Name)
+		}
+		return Product{}, fmt.Errorf("产品名称或代号 %s 对应多个产品（%s），请改为填写产品ID", ref, strings.Join(names, "、"))
+	}
+}
+
+// ResolveStories 将需求中填写的产品名称或代号解析为产品ID（写入 Story.ProductID），返回无法解析的问题
+func (idx *ProductIndex) ResolveStories(stories []story.Story) []PreflightIssue {
+	var issues []PreflightIssue
+	for n := range stories {
+		s := &stories[n]
+		if s.ProductRef == "" {
+			continue
+		}
+		product, err := idx.Resolve(s.ProductRef)
+		if err != nil {
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: "产品ID", Message: err.Error()})
+			continue
+		}
+		s.ProductID = product.ID
+	}
+	return issues
+}
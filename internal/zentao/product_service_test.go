@@ -0,0 +1,68 @@
+package zentao
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestProductIndex_Resolve(t *testing.T) {
+	idx := NewProductIndex([]Product{
+		{ID: 78, Name: "支付中心", Code: "PAY"},
+		{ID: 79, Name: "会员中心", Code: "VIP"},
+		{ID: 80, Name: "会员中心", Code: "VIP2"},
+		{ID: 81, Name: "OPS", Code: "OPS"},
+	})
+
+	tests := []struct {
+		ref     string
+		wantID  int
+		wantErr string
+	}{
+		{ref: "支付中心", wantID: 78},
+		{ref: " PAY ", wantID: 78},
+		{ref: "VIP2", wantID: 80},
+		{ref: "OPS", wantID: 81},
+		{ref: "会员中心", wantErr: "对应多个产品（79 会员中心、80 会员中心）"},
+		{ref: "不存在", wantErr: "不存在或无权限"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			product, err := idx.Resolve(tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve(%q) error = %v, want 包含 %q", tt.ref, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.ref, err)
+			}
+			if product.ID != tt.wantID {
+				t.Errorf("Resolve(%q) = %d, want %d", tt.ref, product.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestProductIndex_ResolveStories(t *testing.T) {
+	idx := NewProductIndex([]Product{{ID: 78, Name: "支付中心", Code: "PAY"}})
+
+	stories := []story.Story{
+		{RowIndex: 1, ProductID: 5},
+		{RowIndex: 2, ProductRef: "PAY"},
+		{RowIndex: 3, ProductRef: "未知产品"},
+	}
+	issues := idx.ResolveStories(stories)
+
+	if stories[0].ProductID != 5 {
+		t.Errorf("行1 填写了产品ID不应改变, 得到 %d", stories[0].ProductID)
+	}
+	if stories[1].ProductID != 78 {
+		t.Errorf("行2 产品ID = %d, want 78", stories[1].ProductID)
+	}
+	if len(issues) != 1 || issues[0].RowIndex != 3 {
+		t.Fatalf("期望行3报告产品不存在, 得到 %v", issues)
+	}
+}
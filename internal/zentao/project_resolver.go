@@ -0,0 +1,248 @@
+// Package zentao 封装禅道API客户端 - 关联项目、执行与产品计划的解析和校验
+package zentao
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// ProjectResolver 将Excel项目、执行、计划列中填写的名称解析为ID，并校验其与需求所属产品的关联关系
+type ProjectResolver struct {
+	logger   *logger.Logger
+	projects ProjectLister
+
+	productProjects map[int][]Project     // 产品ID -> 关联的项目
+	executions      map[int][]Execution   // 项目ID -> 执行
+	plans           map[int][]ProductPlan // 产品ID -> 计划
+	errs            map[string]error      // 查询键 -> 查询错误
+}
+
+// NewProjectResolver 创建新的项目/执行/计划解析器
+func NewProjectResolver(client *Client, log *logger.Logger) *ProjectResolver {
+	return NewProjectResolverWithMocks(log, client.Project)
+}
+
+// NewProjectResolverWithMocks 创建项目/执行/计划解析器（用于测试，直接注入mock实现）
+func NewProjectResolverWithMocks(log *logger.Logger, projects ProjectLister) *ProjectResolver {
+	return &ProjectResolver{
+		logger:          log,
+		projects:        projects,
+		productProjects: make(map[int][]Project),
+		executions:      make(map[int][]Execution),
+		plans:           make(map[int][]ProductPlan),
+		errs:            make(map[string]error),
+	}
+}
+
+// Resolve 解析并校验需求关联的项目、执行和计划（结果写入 Story.Project/Execution/Plan），返回发现的全部问题
+// 项目必须关联需求所属的产品，执行必须属于产品关联的项目（同时填写项目时必须属于该项目），计划必须属于该产品
+// 只填写执行时，所属项目取执行的上级项目
+func (r *ProjectResolver) Resolve(stories []story.Story) []PreflightIssue {
+	var issues []PreflightIssue
+	for idx := range stories {
+		s := &stories[idx]
+		fail := func(field string, err error) {
+			issues = append(issues, PreflightIssue{RowIndex: s.RowIndex, Field: field, Message: err.Error()})
+		}
+
+		if s.Project > 0 || s.ProjectRef != "" {
+			if err := r.resolveProject(s); err != nil {
+				fail("项目", err)
+				continue
+			}
+		}
+		if s.Execution > 0 || s.ExecutionRef != "" {
+			if err := r.resolveExecution(s); err != nil {
+				fail("执行", err)
+			}
+		}
+		if s.Plan > 0 || s.PlanRef != "" {
+			if err := r.resolvePlan(s); err != nil {
+				fail("计划", err)
+			}
+		}
+	}
+	return issues
+}
+
+// resolveProject 在产品关联的项目中按ID或名称（代号）查找项目
+func (r *ProjectResolver) resolveProject(s *story.Story) error {
+	projects, err := r.listProjects(s.ProductID)
+	if err != nil {
+		return err
+	}
+	var matches []string
+	for _, p := range projects {
+		if s.ProjectRef == "" && p.ID == s.Project {
+			return nil
+		}
+		if s.ProjectRef != "" && (strings.TrimSpace(p.Name) == s.ProjectRef || strings.TrimSpace(p.Code) == s.ProjectRef) {
+			s.Project = p.ID
+			matches = append(matches, fmt.Sprintf("%d %s", p.ID, p.Name))
+		}
+	}
+	switch {
+	case s.ProjectRef == "":
+		return fmt.Errorf("项目 %d 不存在或未关联产品 %d", s.Project, s.ProductID)
+	case len(matches) == 0:
+		return fmt.Errorf("项目 %s 不存在或未关联产品 %d", s.ProjectRef, s.ProductID)
+	case len(matches) > 1:
+		s.Project = 0
+		return fmt.Errorf("项目名称 %s 对应多个项目（%s），请改为填写项目ID", s.ProjectRef, strings.Join(sortedStrings(matches), "、"))
+	}
+	r.logger.Debug("行%d 项目 %s 解析为项目ID %d", s.RowIndex, s.ProjectRef, s.Project)
+	return nil
+}
+
+// resolveExecution 在产品关联项目（填写了项目时仅在该项目）的执行中按ID或名称查找执行
+func (r *ProjectResolver) resolveExecution(s *story.Story) error {
+	var projectIDs []int
+	if s.Project > 0 {
+		projectIDs = []int{s.Project}
+	} else {
+		projects, err := r.listProjects(s.ProductID)
+		if err != nil {
+			return err
+		}
+		for _, p := range projects {
+			projectIDs = append(projectIDs, p.ID)
+		}
+	}
+
+	var found []Execution
+	for _, projectID := range projectIDs {
+		executions, err := r.listExecutions(projectID)
+		if err != nil {
+			return err
+		}
+		for _, e := range executions {
+			if (s.ExecutionRef == "" && e.ID == s.Execution) || (s.ExecutionRef != "" && strings.TrimSpace(e.Name) == s.ExecutionRef) {
+				found = append(found, e)
+			}
+		}
+	}
+
+	ref := s.ExecutionRef
+	if ref == "" {
+		ref = fmt.Sprintf("%d", s.Execution)
+	}
+	scope := fmt.Sprintf("产品 %d 关联的项目", s.ProductID)
+	if s.Project > 0 {
+		scope = fmt.Sprintf("项目 %d", s.Project)
+	}
+	switch len(found) {
+	case 0:
+		return fmt.Errorf("执行 %s 不存在或不属于%s", ref, scope)
+	case 1:
+	default:
+		names := make([]string, len(found))
+		for n, e := range found {
+			names[n] = fmt.Sprintf("%d（项目%d）", e.ID, e.Project)
+		}
+		return fmt.Errorf("执行名称 %s 对应多个执行（%s），请改为填写执行ID或同时填写项目", ref, strings.Join(sortedStrings(names), "、"))
+	}
+
+	s.Execution = found[0].ID
+	if s.Project == 0 {
+		s.Project = found[0].Project
+	}
+	r.logger.Debug("行%d 执行 %s 解析为执行ID %d（项目ID %d）", s.RowIndex, ref, s.Execution, s.Project)
+	return nil
+}
+
+// resolvePlan 在产品计划中按ID或名称查找计划
+func (r *ProjectResolver) resolvePlan(s *story.Story) error {
+	plans, err := r.listPlans(s.ProductID)
+	if err != nil {
+		return err
+	}
+	var matches []ProductPlan
+	for _, p := range plans {
+		if (s.PlanRef == "" && p.ID == s.Plan) || (s.PlanRef != "" && strings.TrimSpace(p.Title) == s.PlanRef) {
+			matches = append(matches, p)
+		}
+	}
+
+	ref := s.PlanRef
+	if ref == "" {
+		ref = fmt.Sprintf("%d", s.Plan)
+	}
+	switch len(matches) {
+	case 0:
+		return fmt.Errorf("计划 %s 不存在于产品 %d 中", ref, s.ProductID)
+	case 1:
+	default:
+		names := make([]string, len(matches))
+		for n, p := range matches {
+			names[n] = fmt.Sprintf("%d", p.ID)
+		}
+		return fmt.Errorf("计划名称 %s 对应多个计划（%s），请改为填写计划ID", ref, strings.Join(sortedStrings(names), "、"))
+	}
+	s.Plan = matches[0].ID
+	return nil
+}
+
+// listProjects 返回产品关联的项目（按产品缓存，查询失败时同样缓存错误）
+func (r *ProjectResolver) listProjects(productID int) ([]Project, error) {
+	key := fmt.Sprintf("projects/%d", productID)
+	if err, failed := r.errs[key]; failed {
+		return nil, err
+	}
+	if projects, ok := r.productProjects[productID]; ok {
+		return projects, nil
+	}
+	projects, err := r.projects.ProductProjects(productID)
+	if err != nil {
+		r.errs[key] = fmt.Errorf("无法获取产品 %d 关联的项目: %v", productID, err)
+		return nil, r.errs[key]
+	}
+	r.productProjects[productID] = projects
+	return projects, nil
+}
+
+// listExecutions 返回项目下的执行（按项目缓存，查询失败时同样缓存错误）
+func (r *ProjectResolver) listExecutions(projectID int) ([]Execution, error) {
+	key := fmt.Sprintf("executions/%d", projectID)
+	if err, failed := r.errs[key]; failed {
+		return nil, err
+	}
+	if executions, ok := r.executions[projectID]; ok {
+		return executions, nil
+	}
+	executions, err := r.projects.ProjectExecutions(projectID)
+	if err != nil {
+		r.errs[key] = fmt.Errorf("无法获取项目 %d 的执行列表: %v", projectID, err)
+		return nil, r.errs[key]
+	}
+	r.executions[projectID] = executions
+	return executions, nil
+}
+
+// listPlans 返回产品计划（按产品缓存，查询失败时同样缓存错误）
+func (r *ProjectResolver) listPlans(productID int) ([]ProductPlan, error) {
+	key := fmt.Sprintf("plans/%d", productID)
+	if err, failed := r.errs[key]; failed {
+		return nil, err
+	}
+	if plans, ok := r.plans[productID]; ok {
+		return plans, nil
+	}
+	plans, err := r.projects.ProductPlans(productID)
+	if err != nil {
+		r.errs[key] = fmt.Errorf("无法获取产品 %d 的计划列表: %v", productID, err)
+		return nil, r.errs[key]
+	}
+	r.plans[productID] = plans
+	return plans, nil
+}
+
+// sortedStrings 返回排序后的副本
+func sortedStrings(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,113 @@
+package zentao
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// newTestProjectService 产品1关联项目 10(支付二期) 和 20(会员)，项目10下有执行 101(Sprint 1)、102(Sprint 2)，项目20下有执行 201(Sprint 1)
+func newTestProjectService() *mockProjectService {
+	return &mockProjectService{
+		projectsFn: func(productID int) ([]Project, error) {
+			if productID != 1 {
+				return nil, nil
+			}
+			return []Project{{ID: 10, Name: "支付二期", Code: "PAY2"}, {ID: 20, Name: "会员"}}, nil
+		},
+		executionsFn: func(projectID int) ([]Execution, error) {
+			switch projectID {
+			case 10:
+				return []Execution{{ID: 101, Name: "Sprint 1", Project: 10}, {ID: 102, Name: "Sprint 2", Project: 10}}, nil
+			case 20:
+				return []Execution{{ID: 201, Name: "Sprint 1", Project: 20}}, nil
+			}
+			return nil, errors.New("项目不存在")
+		},
+		plansFn: func(productID int) ([]ProductPlan, error) {
+			return []ProductPlan{{ID: 7, Title: "2026Q1"}}, nil
+		},
+	}
+}
+
+func TestProjectResolver_Resolve(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	resolver := NewProjectResolverWithMocks(log, newTestProjectService())
+
+	stories := []story.Story{
+		{RowIndex: 1, ProductID: 1, ProjectRef: "PAY2", ExecutionRef: "Sprint 1", PlanRef: "2026Q1"},
+		{RowIndex: 2, ProductID: 1, ExecutionRef: "Sprint 2"},
+		{RowIndex: 3, ProductID: 1, Execution: 201, Plan: 7},
+		{RowIndex: 4, ProductID: 1},
+	}
+	if issues := resolver.Resolve(stories); len(issues) != 0 {
+		t.Fatalf("Resolve() 不应有问题, 得到 %v", issues)
+	}
+
+	want := []struct{ project, execution, plan int }{
+		{10, 101, 7},
+		{10, 102, 0}, // 只填写执行时取执行所属项目
+		{20, 201, 7},
+		{0, 0, 0},
+	}
+	for n, w := range want {
+		s := stories[n]
+		if s.Project != w.project || s.Execution != w.execution || s.Plan != w.plan {
+			t.Errorf("行%d 项目/执行/计划 = %d/%d/%d, want %d/%d/%d", s.RowIndex, s.Project, s.Execution, s.Plan, w.project, w.execution, w.plan)
+		}
+	}
+}
+
+func TestProjectResolver_Resolve_Issues(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	resolver := NewProjectResolverWithMocks(log, newTestProjectService())
+
+	stories := []story.Story{
+		{RowIndex: 1, ProductID: 2, Project: 10},                                // 项目未关联产品2
+		{RowIndex: 2, ProductID: 1, ExecutionRef: "Sprint 1"},                   // 两个项目下都有 Sprint 1
+		{RowIndex: 3, ProductID: 1, Project: 20, Execution: 101},                // 执行不属于项目20
+		{RowIndex: 4, ProductID: 1, PlanRef: "2027Q1"},                          // 计划不存在
+		{RowIndex: 5, ProductID: 1, ProjectRef: "会员", ExecutionRef: "Sprint 1"}, // 同时填写项目时不再重名
+	}
+	issues := resolver.Resolve(stories)
+
+	want := []struct {
+		row     int
+		field   string
+		message string
+	}{
+		{1, "项目", "未关联产品 2"},
+		{2, "执行", "对应多个执行"},
+		{3, "执行", "不属于项目 20"},
+		{4, "计划", "不存在于产品 1"},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("期望 %d 个问题, 得到 %d: %v", len(want), len(issues), issues)
+	}
+	for n, w := range want {
+		if issues[n].RowIndex != w.row || issues[n].Field != w.field || !strings.Contains(issues[n].Message, w.message) {
+			t.Errorf("第%d个问题 = %v, want 行%d [%s] 包含 %q", n+1, issues[n], w.row, w.field, w.message)
+		}
+	}
+	if stories[4].Execution != 201 {
+		t.Errorf("行5 执行ID = %d, want 201", stories[4].Execution)
+	}
+}
+
+func TestImporter_BuildStoryRequest_Links(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	importer := NewImporterWithMocks(log, &mockEpicService{}, &mockReqService{}, &mockStoryService{}, &mockConfig{})
+
+	s := story.Story{Type: story.StoryTypeStory, Title: "T", ProductID: 1, Module: -1, Project: 10, Execution: 101, Plan: 7}
+	req := importer.buildStoryRequest(&s)
+	if req.Project != 10 || req.Execution != 101 || req.Plan != 7 {
+		t.Errorf("创建请求 项目/执行/计划 = %d/%d/%d, want 10/101/7", req.Project, req.Execution, req.Plan)
+	}
+}
@@ -0,0 +1,129 @@
+// Package zentao 封装禅道API客户端 - Project项目、执行与产品计划服务
+package zentao
+
+import (
+	"fmt"
+)
+
+// Project 项目信息
+type Project struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Code   string `json:"code"`
+	Status string `json:"status"`
+}
+
+// Execution 执行（迭代/冲刺）信息
+type Execution struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Project int    `json:"project"` // 所属项目ID
+	Status  string `json:"status"`
+}
+
+// ProductPlan 产品计划信息
+type ProductPlan struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+}
+
+// projectListResponse 带分页信息的项目列表响应
+type projectListResponse struct {
+	Status   string    `json:"status"`
+	Projects []Project `json:"projects"`
+	Pager    Pager     `json:"pager"`
+}
+
+// executionListResponse 带分页信息的执行列表响应
+type executionListResponse struct {
+	Status     string      `json:"status"`
+	Executions []Execution `json:"executions"`
+	Pager      Pager       `json:"pager"`
+}
+
+// planListResponse 带分页信息的产品计划列表响应
+type planListResponse struct {
+	Status string        `json:"status"`
+	Plans  []ProductPlan `json:"plans"`
+	Pager  Pager         `json:"pager"`
+}
+
+// ProjectService 项目服务：查询产品关联的项目、项目下的执行和产品计划
+type ProjectService struct {
+	client *Client
+}
+
+// NewProjectService 创建新的项目服务
+func NewProjectService(client *Client) *ProjectService {
+	return &ProjectService{client: client}
+}
+
+// ProductProjects 获取产品关联的所有项目（自动分页）
+// GET /api.php/v2/products/{id}/projects
+func (s *ProjectService) ProductProjects(productID int) ([]Project, error) {
+	var all []Project
+	for pageID := 1; ; pageID++ {
+		var resp projectListResponse
+		if err := s.getPage(fmt.Sprintf("/products/%d/projects", productID), pageID, &resp); err != nil {
+			return nil, fmt.Errorf("获取项目列表失败(页%d): %w", pageID, err)
+		}
+		all = append(all, resp.Projects...)
+		if resp.Pager.PageTotal == 0 || pageID >= resp.Pager.PageTotal {
+			return all, nil
+		}
+	}
+}
+
+// ProjectExecutions 获取项目下的所有执行（自动分页）
+// GET /api.php/v2/projects/{id}/executions
+func (s *ProjectService) ProjectExecutions(projectID int) ([]Execution, error) {
+	var all []Execution
+	for pageID := 1; ; pageID++ {
+		var resp executionListResponse
+		if err := s.getPage(fmt.Sprintf("/projects/%d/executions", projectID), pageID, &resp); err != nil {
+			return nil, fmt.Errorf("获取执行列表失败(页%d): %w", pageID, err)
+		}
+		for _, e := range resp.Executions {
+			if e.Project == 0 {
+				e.Project = projectID
+			}
+			all = append(all, e)
+		}
+		if resp.Pager.PageTotal == 0 || pageID >= resp.Pager.PageTotal {
+			return all, nil
+		}
+	}
+}
+
+// ProductPlans 获取产品的所有计划（自动分页）
+// GET /api.php/v2/products/{id}/plans
+func (s *ProjectService) ProductPlans(productID int) ([]ProductPlan, error) {
+	var all []ProductPlan
+	for pageID := 1; ; pageID++ {
+		var resp planListResponse
+		if err := s.getPage(fmt.Sprintf("/products/%d/plans", productID), pageID, &resp); err != nil {
+			return nil, fmt.Errorf("获取产品计划列表失败(页%d): %w", pageID, err)
+		}
+		all = append(all, resp.Plans...)
+		if resp.Pager.PageTotal == 0 || pageID >= resp.Pager.PageTotal {
+			return all, nil
+		}
+	}
+}
+
+// getPage 请求列表接口的一页数据
+func (s *ProjectService) getPage(path string, pageID int, result interface{}) error {
+	rsp, err := s.client.R().
+		SetQueryParam("recPerPage", "100").
+		SetQueryParam("pageID", fmt.Sprintf("%d", pageID)).
+		SetSuccessResult(result).
+		Get(s.client.RequestURL(path))
+	if err != nil {
+		return err
+	}
+	if rsp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP状态码: %d", rsp.StatusCode)
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ type RequirementCreateRequest struct {
 	Verify     string   `json:"verify,omitempty"`     // 验收标准
 	AssignedTo string   `json:"assignedTo,omitempty"` // 指派给
 	Reviewer   []string `json:"reviewer,omitempty"`   // 评审人
+	Plan       int      `json:"plan,omitempty"`       // 所属产品计划
 }
 
 // RequirementCreateResponse 创建用户需求的响应
@@ -115,6 +116,7 @@ type RequirementListItem struct {
 	Status      string      `json:"status"`
 	Stage       string      `json:"stage"`
 	OpenedBy    string      `json:"openedBy"`
+	Grade       int         `json:"grade"`
 	OpenedDate  string      `json:"openedDate"`
 	AssignedTo  string      `json:"assignedTo"`
 	Spec        string      `json:"spec"`
@@ -0,0 +1,92 @@
+// Package zentao 封装禅道API客户端 - 按检查点日志回滚导入
+package zentao
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// RunJournalPath 将 -run 参数解析为检查点日志路径：可以是运行ID（如 20260101-120000-3f9a1c）或日志文件路径
+func RunJournalPath(run string) string {
+	if strings.HasSuffix(run, ".jsonl") || strings.ContainsAny(run, `/\`) {
+		return run
+	}
+	return JournalPath(run)
+}
+
+// RunID 返回检查点日志对应的运行ID（文件名去掉扩展名）
+func (j *Journal) RunID() string {
+	return strings.TrimSuffix(filepath.Base(j.path), filepath.Ext(j.path))
+}
+
+// RollbackTargets 从检查点日志中找出本次运行创建、尚未回滚的需求，按删除顺序返回
+// 同一行以最后一条记录为准；删除顺序为 Story → Requirement → Epic，同类型内按创建的逆序，保证子需求先于父需求删除
+// 幂等导入中更新或无变化的需求不是本次运行创建的，不会被回滚
+func RollbackTargets(entries []JournalEntry) []JournalEntry {
+	latest := make(map[int]int) // 行号 -> entries下标
+	for idx, e := range entries {
+		latest[e.RowIndex] = idx
+	}
+
+	var targets []JournalEntry
+	for level := story.StoryTypeStory.Level(); level >= story.StoryTypeEpic.Level(); level-- {
+		for idx := len(entries) - 1; idx >= 0; idx-- {
+			e := entries[idx]
+			if latest[e.RowIndex] != idx || e.Status != JournalStatusCreated || e.StoryID <= 0 {
+				continue
+			}
+			if story.StoryType(e.StoryType).Level() == level {
+				targets = append(targets, e)
+			}
+		}
+	}
+	return targets
+}
+
+// SetUpsertMap 设置幂等导入的外部ID映射：回滚删除的需求会从映射中移除，之后的幂等导入会重新创建这些需求
+func (d *Deleter) SetUpsertMap(m *UpsertMap) {
+	d.upsertMap = m
+}
+
+// Rollback 按顺序删除检查点日志中本次运行创建的需求（逐个删除以保证顺序），删除成功的行在日志中记录为已回滚
+func (d *Deleter) Rollback(j *Journal, targets []JournalEntry) []DeleteResult {
+	d.logger.Info("开始回滚运行 %s，共 %d 个需求", j.RunID(), len(targets))
+
+	results := make([]DeleteResult, len(targets))
+	for idx, e := range targets {
+		d.logger.Info("正在回滚第 %d/%d 个需求(行%d)", idx+1, len(targets), e.RowIndex)
+		result := d.DeleteStory(e.StoryID, story.StoryType(e.StoryType))
+		result.Title = e.Title
+		results[idx] = result
+
+		if result.Success {
+			e.Status = JournalStatusRolledBack
+			e.Error = ""
+			e.Time = ""
+			if err := j.Record(e); err != nil {
+				d.logger.Error("写入检查点日志失败(行%d): %v", e.RowIndex, err)
+			}
+		}
+	}
+
+	successCount := 0
+	var deletedIDs []int
+	for idx, result := range results {
+		if result.Success {
+			successCount++
+			deletedIDs = append(deletedIDs, targets[idx].StoryID)
+		}
+	}
+	if d.upsertMap != nil && len(deletedIDs) > 0 {
+		if removed, err := d.upsertMap.RemoveIDs(deletedIDs); err != nil {
+			d.logger.Error("更新外部ID映射文件 %s 失败: %v，请手动删除已回滚需求的记录", d.upsertMap.Path(), err)
+		} else if removed > 0 {
+			d.logger.Info("已从外部ID映射文件 %s 中移除 %d 条已回滚需求的记录", d.upsertMap.Path(), removed)
+		}
+	}
+	d.logger.Info("回滚完成，成功: %d，失败: %d", successCount, len(targets)-successCount)
+
+	return results
+}
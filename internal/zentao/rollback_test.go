@@ -0,0 +1,172 @@
+package zentao
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestRollbackTargets(t *testing.T) {
+	entries := []JournalEntry{
+		{RowIndex: 1, StoryID: 10, StoryType: "epic", Status: JournalStatusCreated},
+		{RowIndex: 2, StoryID: 20, StoryType: "requirement", Status: JournalStatusCreated},
+		{RowIndex: 3, StoryType: "story", Status: JournalStatusFailed},
+		{RowIndex: 4, StoryID: 31, StoryType: "story", Status: JournalStatusCreated},
+		{RowIndex: 3, StoryID: 30, StoryType: "story", Status: JournalStatusCreated}, // 续传时创建
+		{RowIndex: 5, StoryID: 40, StoryType: "story", Status: JournalStatusUpdated}, // 幂等导入更新，不回滚
+		{RowIndex: 6, StoryID: 11, StoryType: "epic", Status: JournalStatusCreated},
+		{RowIndex: 6, StoryID: 11, StoryType: "epic", Status: JournalStatusRolledBack}, // 已回滚
+	}
+
+	targets := RollbackTargets(entries)
+
+	wantIDs := []int{30, 31, 20, 10}
+	if len(targets) != len(wantIDs) {
+		t.Fatalf("期望 %d 个回滚目标, 得到 %d: %+v", len(wantIDs), len(targets), targets)
+	}
+	for idx, id := range wantIDs {
+		if targets[idx].StoryID != id {
+			t.Errorf("回滚顺序 #%d = %d, want %d", idx+1, targets[idx].StoryID, id)
+		}
+	}
+}
+
+func TestDeleter_Rollback(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	path := filepath.Join(t.TempDir(), "20260101-120000.jsonl")
+	j, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("打开检查点日志失败: %v", err)
+	}
+	_ = j.Record(JournalEntry{RowIndex: 1, StoryID: 10, StoryType: "epic", Title: "E", Status: JournalStatusCreated})
+	_ = j.Record(JournalEntry{RowIndex: 2, StoryID: 30, StoryType: "story", Title: "S", Status: JournalStatusCreated})
+
+	var deleted []int
+	okDelete := func(id int) (map[string]interface{}, *req.Response, error) {
+		deleted = append(deleted, id)
+		return nil, nil, nil
+	}
+	mockEpic := &mockEpicService{deleteFn: func(id int) (map[string]interface{}, *req.Response, error) {
+		return nil, nil, fmt.Errorf("无权限")
+	}}
+	mockStorySvc := &mockStoryService{deleteFn: okDelete}
+	deleter := NewDeleterWithMocks(log, mockEpic, &mockReqService{deleteFn: okDelete}, mockStorySvc)
+
+	entries, _ := LoadJournal(path)
+	results := deleter.Rollback(j, RollbackTargets(entries))
+	_ = j.Close()
+
+	if len(results) != 2 || !results[0].Success || results[1].Success {
+		t.Fatalf("期望Story删除成功、Epic删除失败, 得到 %+v", results)
+	}
+	if len(deleted) != 1 || deleted[0] != 30 {
+		t.Errorf("期望仅删除ID 30, 得到 %v", deleted)
+	}
+
+	// 已回滚的行不再是回滚目标，删除失败的行可以重试
+	entries, err = LoadJournal(path)
+	if err != nil {
+		t.Fatalf("读取检查点日志失败: %v", err)
+	}
+	remaining := RollbackTargets(entries)
+	if len(remaining) != 1 || remaining[0].StoryID != 10 {
+		t.Errorf("期望剩余回滚目标为ID 10, 得到 %+v", remaining)
+	}
+	if j.RunID() != "20260101-120000" {
+		t.Errorf("RunID() = %s", j.RunID())
+	}
+}
+
+func TestRunJournalPath(t *testing.T) {
+	if got := RunJournalPath("20260101-120000"); got != filepath.Join(JournalDir, "20260101-120000.jsonl") {
+		t.Errorf("运行ID应解析为日志目录下的文件, 得到 %s", got)
+	}
+	if got := RunJournalPath("other/run.jsonl"); got != "other/run.jsonl" {
+		t.Errorf("路径应原样返回, 得到 %s", got)
+	}
+}
+
+func TestDeleter_Rollback_ThenUpsert(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	dir := t.TempDir()
+	mapPath := filepath.Join(dir, "upsert_map.json")
+
+	createCount := 0
+	var deleted []int
+	mockStorySvc := &mockStoryService{
+		createFn: func(r StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			createCount++
+			return &StoryCreateResponse{Status: "success", ID: 100 + createCount}, nil, nil
+		},
+		updateFn: func(id int, r StoryCreateRequest) (map[string]interface{}, *req.Response, error) {
+			t.Errorf("已回滚的需求不应被更新, ID=%d", id)
+			return map[string]interface{}{"status": "success"}, nil, nil
+		},
+		deleteFn: func(id int) (map[string]interface{}, *req.Response, error) {
+			deleted = append(deleted, id)
+			return nil, nil, nil
+		},
+	}
+	rows := []story.Story{
+		{Type: story.StoryTypeStory, Title: "S1", ProductID: 1, ExternalID: "K-1", RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "S2", ProductID: 1, ExternalID: "K-2", RowIndex: 2},
+	}
+	upsert := func(j *Journal) []ImportResult {
+		m, err := LoadUpsertMap(mapPath)
+		if err != nil {
+			t.Fatalf("加载映射文件失败: %v", err)
+		}
+		importer := NewImporterWithMocks(log, nil, nil, mockStorySvc, &mockConfig{reviewer: "tester"})
+		importer.SetUpsertMap(m)
+		importer.SetJournal(j)
+		return importer.ImportStories(rows)
+	}
+
+	// 第一次导入新建 K-1(101)、K-2(102)
+	j, err := CreateJournal(dir)
+	if err != nil {
+		t.Fatalf("创建检查点日志失败: %v", err)
+	}
+	upsert(j)
+
+	// 回滚该次运行，映射中的记录随之移除
+	m, err := LoadUpsertMap(mapPath)
+	if err != nil {
+		t.Fatalf("加载映射文件失败: %v", err)
+	}
+	deleter := NewDeleterWithMocks(log, &mockEpicService{}, &mockReqService{}, mockStorySvc)
+	deleter.SetUpsertMap(m)
+	entries, _ := LoadJournal(j.Path())
+	deleter.Rollback(j, RollbackTargets(entries))
+	_ = j.Close()
+	if len(deleted) != 2 {
+		t.Fatalf("期望回滚删除2个需求, 得到 %v", deleted)
+	}
+	if reloaded, _ := LoadUpsertMap(mapPath); reloaded.Len() != 0 {
+		t.Errorf("回滚后映射中不应保留已删除需求的记录, 剩余 %d 条", reloaded.Len())
+	}
+
+	// 再次幂等导入：已回滚的行重新创建，而不是视为无变化
+	j, err = CreateJournal(dir)
+	if err != nil {
+		t.Fatalf("创建检查点日志失败: %v", err)
+	}
+	defer j.Close()
+	results := upsert(j)
+	for idx, r := range results {
+		if !r.Success || r.Action != ImportActionCreated {
+			t.Errorf("行%d 应重新创建, 得到 %+v", idx+1, r)
+		}
+	}
+	if createCount != 4 {
+		t.Errorf("期望共创建4次, 得到 %d", createCount)
+	}
+}
@@ -3,29 +3,63 @@ package zentao
 
 import "github.com/imroc/req/v3"
 
-// StoryCreator 研发需求创建/查询接口（用于Importer/Deleter依赖注入）
+// StoryCreator 研发需求创建/修改/查询接口（用于Importer/Deleter依赖注入）
 type StoryCreator interface {
 	Create(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error)
+	UpdateByID(id int, req StoryCreateRequest) (map[string]interface{}, *req.Response, error)
 	ProductsListAll(productID int) ([]StoryListItem, error)
 	DeleteByID(id int) (map[string]interface{}, *req.Response, error)
 }
 
-// EpicCreator 业务需求创建/查询接口
+// EpicCreator 业务需求创建/修改/查询接口
 type EpicCreator interface {
 	Create(req EpicCreateRequest) (*EpicCreateResponse, *req.Response, error)
+	UpdateByID(id int, req EpicCreateRequest) (map[string]interface{}, *req.Response, error)
 	ProductsListAll(productID int) ([]EpicListItem, error)
 	DeleteByID(id int) (map[string]interface{}, *req.Response, error)
 }
 
-// RequirementCreator 用户需求创建/查询接口
+// RequirementCreator 用户需求创建/修改/查询接口
 type RequirementCreator interface {
 	Create(req RequirementCreateRequest) (*RequirementCreateResponse, *req.Response, error)
+	UpdateByID(id int, req RequirementCreateRequest) (map[string]interface{}, *req.Response, error)
 	ProductsListAll(productID int) ([]RequirementListItem, error)
 	DeleteByID(id int) (map[string]interface{}, *req.Response, error)
 }
 
+// ProductGetter 产品查询接口（用于Preflight依赖注入）
+type ProductGetter interface {
+	GetByID(id int) (*Product, error)
+}
+
+// ModuleLister 模块查询接口
+type ModuleLister interface {
+	ListByProduct(productID int) ([]Module, error)
+}
+
+// ModuleManager 模块查询与创建接口
+type ModuleManager interface {
+	ModuleLister
+	Create(req ModuleCreateRequest) (*ModuleCreateResponse, *req.Response, error)
+}
+
+// ProjectLister 项目、执行与产品计划查询接口（用于关联项目/执行/计划的解析与校验）
+type ProjectLister interface {
+	ProductProjects(productID int) ([]Project, error)
+	ProjectExecutions(projectID int) ([]Execution, error)
+	ProductPlans(productID int) ([]ProductPlan, error)
+}
+
+// UserLister 用户查询接口
+type UserLister interface {
+	ListAll() ([]User, error)
+}
+
 // ConfigProvider 配置访问接口（用于测试隔离）
 type ConfigProvider interface {
 	GetDefaultModule() int
 	GetDefaultReviewer() string
+	GetDefaultAssignedTo() string
+	GetUsername() string           // 当前登录账号（用于识别本次运行创建的需求）
+	GetMaxGrade(productID int) int // 产品允许的最大需求层级，0表示不限制
 }
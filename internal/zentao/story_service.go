@@ -26,6 +26,7 @@ type StoryCreateRequest struct {
 	Reviewer   []string `json:"reviewer,omitempty"`   // 评审人
 	Project    int      `json:"project,omitempty"`    // 所属项目
 	Execution  int      `json:"execution,omitempty"`  // 所属执行
+	Plan       int      `json:"plan,omitempty"`       // 所属产品计划
 }
 
 // StoryCreateResponse 创建研发需求的响应
@@ -117,6 +118,7 @@ type StoryListItem struct {
 	Status      string      `json:"status"`
 	Stage       string      `json:"stage"`
 	OpenedBy    string      `json:"openedBy"`
+	Grade       int         `json:"grade"`
 	OpenedDate  string      `json:"openedDate"`
 	AssignedTo  string      `json:"assignedTo"`
 	Spec        string      `json:"spec"`
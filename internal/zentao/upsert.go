@@ -0,0 +1,234 @@
+// Package zentao 封装禅道API客户端 - 基于外部ID的幂等导入
+package zentao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+// DefaultUpsertMapPath 外部ID映射文件的默认路径
+const DefaultUpsertMapPath = "upsert_map.json"
+
+// 导入动作（ImportResult.Action）
+const (
+	ImportActionCreated   = "created"   // 新建
+	ImportActionUpdated   = "updated"   // 已存在且内容有变化，已更新
+	ImportActionUnchanged = "unchanged" // 已存在且内容与上次导入时一致，未调用API（不检查禅道中的直接修改）
+)
+
+// UpsertRecord 外部ID对应的禅道需求记录
+type UpsertRecord struct {
+	StoryID   int    `json:"id"`
+	StoryType string `json:"type"`
+	ProductID int    `json:"product"`
+	Hash      string `json:"hash"` // 最近一次提交的请求体摘要，用于判断内容是否变化
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// UpsertMap 外部ID到禅道需求的本地映射文件（JSON格式），每次变更后立即保存
+type UpsertMap struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]UpsertRecord
+}
+
+// LoadUpsertMap 加载外部ID映射文件，文件不存在时返回空映射
+func LoadUpsertMap(path string) (*UpsertMap, error) {
+	m := &UpsertMap{path: path, records: make(map[string]UpsertRecord)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取外部ID映射文件失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &m.records); err != nil {
+		return nil, fmt.Errorf("解析外部ID映射文件失败: %w", err)
+	}
+	return m, nil
+}
+
+// Path 返回映射文件路径
+func (m *UpsertMap) Path() string {
+	return m.path
+}
+
+// Len 返回映射中的记录数
+func (m *UpsertMap) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.records)
+}
+
+// Get 查询外部ID对应的记录
+func (m *UpsertMap) Get(externalID string) (UpsertRecord, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[externalID]
+	return rec, ok
+}
+
+// Put 写入外部ID对应的记录并保存到文件（先写临时文件再重命名，避免中断时损坏）
+func (m *UpsertMap) Put(externalID string, rec UpsertRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rec.UpdatedAt = time.Now().Format(time.RFC3339)
+	m.records[externalID] = rec
+	return m.save()
+}
+
+// RemoveIDs 删除映射到指定禅道ID的记录（如回滚删除的需求）并保存，返回删除的记录数；没有匹配的记录时不写文件
+// 否则下次幂等导入会把已删除的需求当作已存在，跳过或更新一个不存在的ID，而不是重新创建
+func (m *UpsertMap) RemoveIDs(ids []int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	removed := 0
+	for externalID, rec := range m.records {
+		if slices.Contains(ids, rec.StoryID) {
+			delete(m.records, externalID)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, m.save()
+}
+
+// save 将全部记录写入映射文件（调用方需持有锁）
+func (m *UpsertMap) save() error {
+	data, err := json.MarshalIndent(m.records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化外部ID映射失败: %w", err)
+	}
+	if dir := filepath.Dir(m.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建外部ID映射目录失败: %w", err)
+		}
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入外部ID映射文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("保存外部ID映射文件失败: %w", err)
+	}
+	return nil
+}
+
+// SetUpsertMap 启用幂等导入：带外部ID且已在映射中的行执行更新（内容无变化时跳过），其余行新建
+func (i *Importer) SetUpsertMap(m *UpsertMap) {
+	i.upsertMap = m
+}
+
+// upsertExisting 更新外部ID已映射的需求，请求体与上次提交一致时不调用API
+// "无变化" 只比较本地映射中记录的上次请求摘要，不查询禅道中需求的当前内容：
+// 在禅道中直接修改过的需求，只有Excel内容也发生变化时才会被覆盖
+func (i *Importer) upsertExisting(s *story.Story, rec UpsertRecord) ImportResult {
+	start := time.Now()
+	result := ImportResult{StoryType: string(s.Type), StoryID: rec.StoryID}
+
+	if rec.StoryType != string(s.Type) || rec.ProductID != s.ProductID {
+		result.Error = fmt.Errorf("外部ID %s 已映射到 [%s] 产品%d 的需求 %d，与当前行 [%s] 产品%d 不一致",
+			s.ExternalID, rec.StoryType, rec.ProductID, rec.StoryID, s.Type, s.ProductID)
+		i.logger.Error("%v", result.Error)
+		result.ElapsedTime = time.Since(start)
+		return result
+	}
+
+	hash := i.requestHash(s)
+	if hash == rec.Hash {
+		i.logger.Info("%s与上次导入相比无变化，跳过(外部ID: %s, ID: %d): %s", s.GetTypeString(), s.ExternalID, rec.StoryID, s.Title)
+		result.Success = true
+		result.Action = ImportActionUnchanged
+		result.ElapsedTime = time.Since(start)
+		return result
+	}
+
+	i.logger.Info("正在更新%s(外部ID: %s, ID: %d): %s", s.GetTypeString(), s.ExternalID, rec.StoryID, s.Title)
+
+	var resp map[string]interface{}
+	var rsp *req.Response
+	var err error
+	switch s.Type {
+	case story.StoryTypeEpic:
+		resp, rsp, err = i.epicCreator.UpdateByID(rec.StoryID, i.buildEpicRequest(s))
+	case story.StoryTypeRequirement:
+		resp, rsp, err = i.reqCreator.UpdateByID(rec.StoryID, i.buildRequirementRequest(s))
+	default:
+		resp, rsp, err = i.storyCreator.UpdateByID(rec.StoryID, i.buildStoryRequest(s))
+	}
+	if err == nil && rsp != nil && rsp.StatusCode >= 400 {
+		err = fmt.Errorf("HTTP状态码: %d", rsp.StatusCode)
+	}
+	if err == nil {
+		if status, ok := resp["status"].(string); ok && status != "success" {
+			err = fmt.Errorf("API返回失败状态: %s", status)
+		}
+	}
+
+	if err != nil {
+		i.logger.ErrorWithDetail("需求更新失败", err, map[string]interface{}{
+			"需求类型":    s.GetTypeString(),
+			"需求ID":    rec.StoryID,
+			"外部ID":    s.ExternalID,
+			"HTTP状态码": i.getStatusCode(rsp),
+			"响应内容":    i.getResponseBody(rsp),
+		})
+		result.Error = fmt.Errorf("更新%s失败: %v", s.GetTypeString(), err)
+		result.ResponseMsg = i.getResponseBody(rsp)
+	} else {
+		i.logger.Success("%s更新成功，ID: %d", s.GetTypeString(), rec.StoryID)
+		result.Success = true
+		result.Action = ImportActionUpdated
+		rec.Hash = hash
+		if err := i.upsertMap.Put(s.ExternalID, rec); err != nil {
+			i.logger.Error("保存外部ID映射失败(外部ID: %s): %v", s.ExternalID, err)
+		}
+	}
+
+	result.ElapsedTime = time.Since(start)
+	return result
+}
+
+// rememberCreated 记录新建需求的外部ID映射
+func (i *Importer) rememberCreated(s *story.Story, storyID int) {
+	rec := UpsertRecord{
+		StoryID:   storyID,
+		StoryType: string(s.Type),
+		ProductID: s.ProductID,
+		Hash:      i.requestHash(s),
+	}
+	if err := i.upsertMap.Put(s.ExternalID, rec); err != nil {
+		i.logger.Error("保存外部ID映射失败(外部ID: %s): %v", s.ExternalID, err)
+	}
+}
+
+// requestHash 计算需求对应创建请求体的摘要
+func (i *Importer) requestHash(s *story.Story) string {
+	var payload interface{}
+	switch s.Type {
+	case story.StoryTypeEpic:
+		payload = i.buildEpicRequest(s)
+	case story.StoryTypeRequirement:
+		payload = i.buildRequirementRequest(s)
+	default:
+		payload = i.buildStoryRequest(s)
+	}
+	data, _ := json.Marshal(payload)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
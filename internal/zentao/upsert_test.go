@@ -0,0 +1,93 @@
+package zentao
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/imroc/req/v3"
+	"github.com/jan2xue/zentao_import_story/internal/logger"
+	"github.com/jan2xue/zentao_import_story/pkg/story"
+)
+
+func TestImporter_ImportStories_Upsert(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+	mapPath := filepath.Join(t.TempDir(), "upsert_map.json")
+
+	var createCount, updateCount int
+	mockStorySvc := &mockStoryService{
+		createFn: func(r StoryCreateRequest) (*StoryCreateResponse, *req.Response, error) {
+			createCount++
+			return &StoryCreateResponse{Status: "success", ID: 100 + createCount}, nil, nil
+		},
+		updateFn: func(id int, r StoryCreateRequest) (map[string]interface{}, *req.Response, error) {
+			updateCount++
+			if id != 101 {
+				t.Errorf("期望更新ID=101, 得到 %d", id)
+			}
+			return map[string]interface{}{"status": "success"}, nil, nil
+		},
+	}
+
+	run := func(stories []story.Story) []ImportResult {
+		m, err := LoadUpsertMap(mapPath)
+		if err != nil {
+			t.Fatalf("加载映射文件失败: %v", err)
+		}
+		importer := NewImporterWithMocks(log, nil, nil, mockStorySvc, &mockConfig{reviewer: "tester"})
+		importer.SetUpsertMap(m)
+		return importer.ImportStories(stories)
+	}
+
+	rows := []story.Story{
+		{Type: story.StoryTypeStory, Title: "S1", ProductID: 1, ExternalID: "K-1", RowIndex: 1},
+		{Type: story.StoryTypeStory, Title: "S2", ProductID: 1, RowIndex: 2},
+	}
+
+	// 第一次：全部新建，带外部ID的行写入映射
+	results := run(rows)
+	if createCount != 2 || results[0].Action != ImportActionCreated {
+		t.Fatalf("第一次导入应新建2条, create=%d, action=%s", createCount, results[0].Action)
+	}
+
+	// 第二次：K-1无变化不调用API，无外部ID的行仍新建
+	results = run(rows)
+	if results[0].Action != ImportActionUnchanged || results[0].StoryID != 101 {
+		t.Errorf("K-1应无变化且ID=101, 得到 %+v", results[0])
+	}
+	if createCount != 3 || updateCount != 0 {
+		t.Errorf("期望create=3, update=0, 得到 create=%d, update=%d", createCount, updateCount)
+	}
+
+	// 第三次：K-1标题变化，调用UpdateByID
+	rows[0].Title = "S1-改"
+	results = run(rows[:1])
+	if results[0].Action != ImportActionUpdated || updateCount != 1 {
+		t.Errorf("K-1应被更新, 得到 %+v, update=%d", results[0], updateCount)
+	}
+
+	importer := &Importer{logger: log}
+	report := importer.GenerateReport(results)
+	if !bytes.Contains([]byte(report), []byte("更新: 1")) {
+		t.Errorf("报告应包含更新统计:\n%s", report)
+	}
+}
+
+func TestImporter_ImportStories_UpsertTypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLoggerWithWriter(&buf)
+
+	m, _ := LoadUpsertMap(filepath.Join(t.TempDir(), "upsert_map.json"))
+	_ = m.Put("K-1", UpsertRecord{StoryID: 9, StoryType: "epic", ProductID: 1})
+
+	importer := NewImporterWithMocks(log, nil, nil, &mockStoryService{}, &mockConfig{})
+	importer.SetUpsertMap(m)
+
+	results := importer.ImportStories([]story.Story{
+		{Type: story.StoryTypeStory, Title: "S1", ProductID: 1, ExternalID: "K-1", RowIndex: 1},
+	})
+	if results[0].Success {
+		t.Error("外部ID对应的需求类型不一致时应失败")
+	}
+}
@@ -0,0 +1,139 @@
+// Package zentao 封装禅道API客户端 - User用户服务
+package zentao
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/imroc/req/v3"
+)
+
+// User 用户信息
+type User struct {
+	ID       int    `json:"id"`
+	Account  string `json:"account"`
+	Realname string `json:"realname"`
+	Role     string `json:"role"`
+	Dept     int    `json:"dept"`
+	Email    string `json:"email"`
+	Deleted  string `json:"deleted"`
+}
+
+// UserListWithPagerResponse 带分页信息的用户列表响应
+type UserListWithPagerResponse struct {
+	Status string `json:"status"`
+	Users  []User `json:"users"`
+	Pager  Pager  `json:"pager"`
+}
+
+// UserService 用户服务
+type UserService struct {
+	client *Client
+}
+
+// NewUserService 创建新的用户服务
+func NewUserService(client *Client) *UserService {
+	return &UserService{client: client}
+}
+
+// List 获取用户列表（单页）
+// GET /api.php/v2/users
+func (s *UserService) List(opts *ListOptions) (*UserListWithPagerResponse, *req.Response, error) {
+	var resp UserListWithPagerResponse
+	req := s.client.R().SetSuccessResult(&resp)
+
+	if opts != nil {
+		if opts.RecPerPage > 0 {
+			req.SetQueryParam("recPerPage", fmt.Sprintf("%d", opts.RecPerPage))
+		}
+		if opts.PageID > 0 {
+			req.SetQueryParam("pageID", fmt.Sprintf("%d", opts.PageID))
+		}
+	}
+
+	rsp, err := req.Get(s.client.RequestURL("/users"))
+	if err != nil {
+		return nil, rsp, err
+	}
+	return &resp, rsp, nil
+}
+
+// ListAll 获取所有用户（自动分页）
+// GET /api.php/v2/users
+func (s *UserService) ListAll() ([]User, error) {
+	var allUsers []User
+	pageID := 1
+	pageSize := 100
+
+	for {
+		resp, _, err := s.List(&ListOptions{
+			PageID:     pageID,
+			RecPerPage: pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("获取用户列表失败(页%d): %w", pageID, err)
+		}
+
+		allUsers = append(allUsers, resp.Users...)
+
+		if resp.Pager.PageTotal == 0 || pageID >= resp.Pager.PageTotal {
+			break
+		}
+		pageID++
+	}
+
+	return allUsers, nil
+}
+
+// Index 获取所有用户并建立 账号/姓名 索引（用于将Excel中填写的姓名解析为账号）
+func (s *UserService) Index() (*UserIndex, error) {
+	users, err := s.ListAll()
+	if err != nil {
+		return nil, err
+	}
+	return NewUserIndex(users), nil
+}
+
+// UserIndex 用户索引：按账号或姓名(realname)查找账号，已删除的用户不参与匹配
+type UserIndex struct {
+	accounts  map[string]bool     // 有效账号集合
+	realnames map[string][]string // 姓名 -> 账号列表（重名时有多个）
+}
+
+// NewUserIndex 根据用户列表建立索引
+func NewUserIndex(users []User) *UserIndex {
+	idx := &UserIndex{
+		accounts:  make(map[string]bool, len(users)),
+		realnames: make(map[string][]string),
+	}
+	for _, u := range users {
+		if u.Deleted == "1" || u.Account == "" {
+			continue
+		}
+		idx.accounts[u.Account] = true
+		if name := strings.TrimSpace(u.Realname); name != "" {
+			idx.realnames[name] = append(idx.realnames[name], u.Account)
+		}
+	}
+	return idx
+}
+
+// Resolve 将账号或姓名解析为账号：优先按账号精确匹配，否则按姓名匹配
+// 姓名对应多个账号（重名）或找不到时返回错误
+func (idx *UserIndex) Resolve(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if idx.accounts[name] {
+		return name, nil
+	}
+	switch accounts := idx.realnames[name]; len(accounts) {
+	case 0:
+		return "", fmt.Errorf("账号或姓名 %s 不存在或已删除", name)
+	case 1:
+		return accounts[0], nil
+	default:
+		sorted := append([]string(nil), accounts...)
+		sort.Strings(sorted)
+		return "", fmt.Errorf("姓名 %s 对应多个账号（%s），请改为填写账号", name, strings.Join(sorted, "、"))
+	}
+}
@@ -0,0 +1,37 @@
+package zentao
+
+import "testing"
+
+func TestUserIndex_Resolve(t *testing.T) {
+	idx := NewUserIndex([]User{
+		{Account: "zhangsan", Realname: "张三"},
+		{Account: "zhangsan2", Realname: "张三"},
+		{Account: "lisi", Realname: "李四"},
+		{Account: "wangwu", Realname: "王五", Deleted: "1"},
+		{Account: "lisi2", Realname: "lisi"},
+	})
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"lisi", "lisi", ""}, // 账号优先于同名的姓名
+		{" 李四 ", "lisi", ""},
+		{"张三", "", "姓名 张三 对应多个账号（zhangsan、zhangsan2），请改为填写账号"},
+		{"王五", "", "账号或姓名 王五 不存在或已删除"},
+		{"wangwu", "", "账号或姓名 wangwu 不存在或已删除"},
+	}
+	for _, tt := range tests {
+		got, err := idx.Resolve(tt.name)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Resolve(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
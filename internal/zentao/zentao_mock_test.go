@@ -6,6 +6,7 @@ import (
 
 // mockEpicService 实现 EpicCreator 接口
 type mockEpicService struct {
+	updateFn func(id int, req EpicCreateRequest) (map[string]interface{}, *req.Response, error)
 	createFn func(req EpicCreateRequest) (*EpicCreateResponse, *req.Response, error)
 	deleteFn func(id int) (map[string]interface{}, *req.Response, error)
 	listFn   func(productID int) ([]EpicListItem, error)
@@ -15,6 +16,10 @@ func (m *mockEpicService) Create(req EpicCreateRequest) (*EpicCreateResponse, *r
 	return m.createFn(req)
 }
 
+func (m *mockEpicService) UpdateByID(id int, req EpicCreateRequest) (map[string]interface{}, *req.Response, error) {
+	return m.updateFn(id, req)
+}
+
 func (m *mockEpicService) DeleteByID(id int) (map[string]interface{}, *req.Response, error) {
 	return m.deleteFn(id)
 }
@@ -25,6 +30,7 @@ func (m *mockEpicService) ProductsListAll(productID int) ([]EpicListItem, error)
 
 // mockReqService 实现 RequirementCreator 接口
 type mockReqService struct {
+	updateFn func(id int, req RequirementCreateRequest) (map[string]interface{}, *req.Response, error)
 	createFn func(req RequirementCreateRequest) (*RequirementCreateResponse, *req.Response, error)
 	deleteFn func(id int) (map[string]interface{}, *req.Response, error)
 	listFn   func(productID int) ([]RequirementListItem, error)
@@ -34,6 +40,10 @@ func (m *mockReqService) Create(req RequirementCreateRequest) (*RequirementCreat
 	return m.createFn(req)
 }
 
+func (m *mockReqService) UpdateByID(id int, req RequirementCreateRequest) (map[string]interface{}, *req.Response, error) {
+	return m.updateFn(id, req)
+}
+
 func (m *mockReqService) DeleteByID(id int) (map[string]interface{}, *req.Response, error) {
 	return m.deleteFn(id)
 }
@@ -44,6 +54,7 @@ func (m *mockReqService) ProductsListAll(productID int) ([]RequirementListItem,
 
 // mockStoryService 实现 StoryCreator 接口
 type mockStoryService struct {
+	updateFn func(id int, req StoryCreateRequest) (map[string]interface{}, *req.Response, error)
 	createFn func(req StoryCreateRequest) (*StoryCreateResponse, *req.Response, error)
 	deleteFn func(id int) (map[string]interface{}, *req.Response, error)
 	listFn   func(productID int) ([]StoryListItem, error)
@@ -53,6 +64,10 @@ func (m *mockStoryService) Create(req StoryCreateRequest) (*StoryCreateResponse,
 	return m.createFn(req)
 }
 
+func (m *mockStoryService) UpdateByID(id int, req StoryCreateRequest) (map[string]interface{}, *req.Response, error) {
+	return m.updateFn(id, req)
+}
+
 func (m *mockStoryService) DeleteByID(id int) (map[string]interface{}, *req.Response, error) {
 	return m.deleteFn(id)
 }
@@ -65,7 +80,64 @@ func (m *mockStoryService) ProductsListAll(productID int) ([]StoryListItem, erro
 type mockConfig struct {
 	module   int
 	reviewer string
+	assignee string
+	username string
+	maxGrade int
+}
+
+func (m *mockConfig) GetDefaultModule() int        { return m.module }
+func (m *mockConfig) GetDefaultReviewer() string   { return m.reviewer }
+func (m *mockConfig) GetDefaultAssignedTo() string { return m.assignee }
+func (m *mockConfig) GetUsername() string          { return m.username }
+func (m *mockConfig) GetMaxGrade(int) int          { return m.maxGrade }
+
+// mockProductService 实现 ProductGetter 接口
+type mockProductService struct {
+	getFn func(id int) (*Product, error)
+}
+
+func (m *mockProductService) GetByID(id int) (*Product, error) {
+	return m.getFn(id)
+}
+
+// mockModuleService 实现 ModuleManager 接口
+type mockModuleService struct {
+	listFn   func(productID int) ([]Module, error)
+	createFn func(req ModuleCreateRequest) (*ModuleCreateResponse, *req.Response, error)
+}
+
+func (m *mockModuleService) ListByProduct(productID int) ([]Module, error) {
+	return m.listFn(productID)
+}
+
+func (m *mockModuleService) Create(req ModuleCreateRequest) (*ModuleCreateResponse, *req.Response, error) {
+	return m.createFn(req)
+}
+
+// mockProjectService 实现 ProjectLister 接口
+type mockProjectService struct {
+	projectsFn   func(productID int) ([]Project, error)
+	executionsFn func(projectID int) ([]Execution, error)
+	plansFn      func(productID int) ([]ProductPlan, error)
+}
+
+func (m *mockProjectService) ProductProjects(productID int) ([]Project, error) {
+	return m.projectsFn(productID)
 }
 
-func (m *mockConfig) GetDefaultModule() int      { return m.module }
-func (m *mockConfig) GetDefaultReviewer() string { return m.reviewer }
+func (m *mockProjectService) ProjectExecutions(projectID int) ([]Execution, error) {
+	return m.executionsFn(projectID)
+}
+
+func (m *mockProjectService) ProductPlans(productID int) ([]ProductPlan, error) {
+	return m.plansFn(productID)
+}
+
+// mockUserService 实现 UserLister 接口
+type mockUserService struct {
+	listFn func() ([]User, error)
+}
+
+func (m *mockUserService) ListAll() ([]User, error) {
+	return m.listFn()
+}
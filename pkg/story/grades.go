@@ -0,0 +1,64 @@
+package story
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AssignGrades 按 "@行号" 父需求链计算每行需求的层级(Grade)：父需求是同类型的行时为父需求层级+1，否则为1
+// 父需求为禅道ID时无法离线确定其类型和层级，暂记为1，导入时再按禅道中的父需求修正
+func AssignGrades(stories []Story) {
+	grades := computeGrades(stories)
+	for idx := range stories {
+		stories[idx].Grade = grades[stories[idx].RowIndex]
+	}
+}
+
+// ValidateGrades 校验每行需求的层级不超过所属产品允许的最大层级，返回全部问题（按行号排序）
+// maxGrade 返回产品允许的最大层级，<=0 表示不限制
+func ValidateGrades(stories []Story, maxGrade func(productID int) int) []ReferenceIssue {
+	var issues []ReferenceIssue
+	grades := computeGrades(stories)
+	for _, s := range stories {
+		limit := maxGrade(s.ProductID)
+		if grade := grades[s.RowIndex]; limit > 0 && grade > limit {
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Message: fmt.Sprintf("%s层级为 %d，超过产品 %d 允许的最大层级 %d（父需求引用 %s）",
+				s.GetTypeString(), grade, s.ProductID, limit, s.ParentRef)})
+		}
+	}
+	sort.SliceStable(issues, func(a, b int) bool { return issues[a].RowIndex < issues[b].RowIndex })
+	return issues
+}
+
+// computeGrades 计算每行需求的层级（行号 -> 层级），引用成环时在环上截断，保证计算能够结束
+func computeGrades(stories []Story) map[int]int {
+	byRow := make(map[int]*Story, len(stories))
+	for idx := range stories {
+		byRow[stories[idx].RowIndex] = &stories[idx]
+	}
+
+	grades := make(map[int]int, len(stories))
+	visiting := make(map[int]bool)
+	var gradeOf func(row int) int
+	gradeOf = func(row int) int {
+		if grade, ok := grades[row]; ok {
+			return grade
+		}
+		s := byRow[row]
+		grade := 1
+		if parentRow, isRowRef, err := ParseRowRef(s.ParentRef); isRowRef && err == nil && !visiting[row] {
+			if parent, ok := byRow[parentRow]; ok && parent.Type == s.Type {
+				visiting[row] = true
+				grade = gradeOf(parentRow) + 1
+				visiting[row] = false
+			}
+		}
+		grades[row] = grade
+		return grade
+	}
+
+	for _, s := range stories {
+		gradeOf(s.RowIndex)
+	}
+	return grades
+}
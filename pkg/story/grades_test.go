@@ -0,0 +1,55 @@
+package story
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssignGrades(t *testing.T) {
+	stories := []Story{
+		{Type: StoryTypeEpic, RowIndex: 1},
+		{Type: StoryTypeRequirement, RowIndex: 2, ParentRef: "@1"},
+		{Type: StoryTypeRequirement, RowIndex: 3, ParentRef: "@2"},
+		{Type: StoryTypeStory, RowIndex: 4, ParentRef: "@6"}, // 父需求在后面的行
+		{Type: StoryTypeStory, RowIndex: 5, ParentRef: "@4"},
+		{Type: StoryTypeStory, RowIndex: 6, ParentRef: "@3"},
+		{Type: StoryTypeStory, RowIndex: 7, ParentRef: "100"},
+		{Type: StoryTypeStory, RowIndex: 8, ParentRef: "@9"}, // 成环
+		{Type: StoryTypeStory, RowIndex: 9, ParentRef: "@8"},
+	}
+
+	AssignGrades(stories)
+
+	want := []int{1, 1, 2, 2, 3, 1, 1}
+	for idx, grade := range want {
+		if stories[idx].Grade != grade {
+			t.Errorf("行%d Grade = %d, want %d", stories[idx].RowIndex, stories[idx].Grade, grade)
+		}
+	}
+	for _, s := range stories[7:] {
+		if s.Grade < 1 {
+			t.Errorf("成环的行%d 也应得到层级, 得到 %d", s.RowIndex, s.Grade)
+		}
+	}
+}
+
+func TestValidateGrades(t *testing.T) {
+	stories := []Story{
+		{Type: StoryTypeStory, RowIndex: 1, ProductID: 1},
+		{Type: StoryTypeStory, RowIndex: 2, ProductID: 1, ParentRef: "@1"},
+		{Type: StoryTypeStory, RowIndex: 3, ProductID: 1, ParentRef: "@2"},
+		{Type: StoryTypeStory, RowIndex: 4, ProductID: 2},
+		{Type: StoryTypeStory, RowIndex: 5, ProductID: 2, ParentRef: "@4"},
+		{Type: StoryTypeStory, RowIndex: 6, ProductID: 2, ParentRef: "@5"},
+	}
+	maxGrade := map[int]int{1: 2} // 产品2不限制
+
+	issues := ValidateGrades(stories, func(productID int) int { return maxGrade[productID] })
+
+	if len(issues) != 1 {
+		t.Fatalf("期望 1 个问题, 得到 %d: %v", len(issues), issues)
+	}
+	if got := issues[0].String(); !strings.HasPrefix(got, "行3: 研发需求(Story)层级为 3，超过产品 1 允许的最大层级 2") {
+		t.Errorf("问题描述 = %q", got)
+	}
+}
@@ -0,0 +1,253 @@
+package story
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ReferenceIssue 父需求引用问题
+type ReferenceIssue struct {
+	RowIndex int    // 出现问题的行号
+	Field    string // 出现问题的字段：IssueFieldParent 或 IssueFieldRefKey，为空表示不对应单个字段
+	Message  string // 问题描述
+}
+
+// ReferenceIssue.Field 的取值
+const (
+	IssueFieldParent = "parent" // 父需求引用
+	IssueFieldRefKey = "refKey" // 引用键
+)
+
+// String 返回问题描述
+func (r ReferenceIssue) String() string {
+	return fmt.Sprintf("行%d: %s", r.RowIndex, r.Message)
+}
+
+// ParseRowRef 解析 "@n" 格式的父需求引用
+// 返回引用的行号；ref 不是 "@" 开头时 isRowRef 为 false
+func ParseRowRef(ref string) (row int, isRowRef bool, err error) {
+	if !strings.HasPrefix(ref, "@") {
+		return 0, false, nil
+	}
+	row, err = strconv.Atoi(strings.TrimPrefix(ref, "@"))
+	if err != nil || row <= 0 {
+		return 0, true, fmt.Errorf("无效的父需求引用格式: %s，应为 @行号 或 @引用键", ref)
+	}
+	return row, true, nil
+}
+
+// ParseSheetRowRef 解析 "@n" 或跨工作表的 "@工作表!n" 父需求引用，n 为该工作表的数据行号
+// sheet 为空表示当前工作表；ref 不是 "@" 开头时 isRowRef 为 false
+func ParseSheetRowRef(ref string) (sheet string, row int, isRowRef bool, err error) {
+	if !strings.HasPrefix(ref, "@") {
+		return "", 0, false, nil
+	}
+	body := strings.TrimPrefix(ref, "@")
+	if sep := strings.LastIndex(body, "!"); sep >= 0 {
+		sheet, body = strings.TrimSpace(body[:sep]), body[sep+1:]
+	}
+	row, err = strconv.Atoi(strings.TrimSpace(body))
+	if err != nil || row <= 0 || (strings.Contains(ref, "!") && sheet == "") {
+		return "", 0, true, fmt.Errorf("无效的父需求引用格式: %s，应为 @行号、@工作表!行号 或 @引用键", ref)
+	}
+	return sheet, row, true, nil
+}
+
+// ValidateRefKey 校验引用键：不能为纯数字（与 "@行号" 混淆），不能包含空白、"@" 或 "!"（与 "@工作表!行号" 混淆）
+func ValidateRefKey(key string) error {
+	if _, err := strconv.Atoi(key); err == nil {
+		return fmt.Errorf("引用键 %s 不能为纯数字（会与 @行号 混淆）", key)
+	}
+	if key == "" || strings.ContainsAny(key, "@!") || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("引用键 %s 不能包含空白、@ 或 !", key)
+	}
+	return nil
+}
+
+// ParseKeyRef 解析 "@引用键" 格式的父需求引用，如 "@PAY-EPIC-1"
+// ref 不是 "@" 开头、为 "@行号"/"@工作表!行号" 或引用键不合法时 isKeyRef 为 false
+func ParseKeyRef(ref string) (key string, isKeyRef bool) {
+	if !strings.HasPrefix(ref, "@") {
+		return "", false
+	}
+	key = strings.TrimPrefix(ref, "@")
+	if ValidateRefKey(key) != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// TitleRefPrefix "#title:标题" 格式父需求引用的前缀，按标题引用产品中已有的业务需求或用户需求
+const TitleRefPrefix = "#title:"
+
+// ParseTitleRef 解析 "#title:标题" 格式的父需求引用（前缀不区分大小写，冒号可为全角）
+// ref 不是该格式时 isTitleRef 为 false；标题为空时返回错误
+func ParseTitleRef(ref string) (title string, isTitleRef bool, err error) {
+	lower := strings.ToLower(ref)
+	var rest string
+	switch {
+	case strings.HasPrefix(lower, TitleRefPrefix):
+		rest = ref[len(TitleRefPrefix):]
+	case strings.HasPrefix(lower, "#title："):
+		rest = ref[len("#title："):]
+	default:
+		return "", false, nil
+	}
+	if title = strings.TrimSpace(rest); title == "" {
+		return "", true, fmt.Errorf("父需求引用 %s 缺少标题，应为 #title:标题", ref)
+	}
+	return title, true, nil
+}
+
+// IndexKeys 建立引用键索引：引用键 -> 填写该引用键的行号（重复的引用键对应多行）
+func IndexKeys(stories []Story) map[string][]int {
+	keys := make(map[string][]int)
+	for _, s := range stories {
+		if s.RefKey != "" {
+			keys[s.RefKey] = append(keys[s.RefKey], s.RowIndex)
+		}
+	}
+	return keys
+}
+
+// ResolveKeyRefs 将 "@引用键" 父需求引用改写为 "@行号"（原引用键保存在 ParentKey），返回重复的引用键和无法解析的引用
+// 已改写或不是引用键格式的引用保持不变
+func ResolveKeyRefs(stories []Story) []ReferenceIssue {
+	keys := IndexKeys(stories)
+	issues := duplicateKeyIssues(stories, keys)
+	for idx := range stories {
+		s := &stories[idx]
+		key, isKeyRef := ParseKeyRef(s.ParentRef)
+		if !isKeyRef {
+			continue
+		}
+		row, err := keyRow(s.ParentRef, key, keys)
+		if err != nil {
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldParent, Message: err.Error()})
+			continue
+		}
+		s.ParentKey = key
+		s.ParentRef = "@" + strconv.Itoa(row)
+	}
+	sort.SliceStable(issues, func(a, b int) bool { return issues[a].RowIndex < issues[b].RowIndex })
+	return issues
+}
+
+// keyRow 返回引用键对应的行号，引用键不存在或重复时返回错误
+func keyRow(ref, key string, keys map[string][]int) (int, error) {
+	switch rows := keys[key]; len(rows) {
+	case 0:
+		return 0, fmt.Errorf("父需求引用 %s 指向的引用键不存在", ref)
+	case 1:
+		return rows[0], nil
+	default:
+		return 0, fmt.Errorf("父需求引用 %s 对应多行（%s），引用键重复", ref, rowList(rows))
+	}
+}
+
+// duplicateKeyIssues 为每个使用了重复引用键的行生成问题
+func duplicateKeyIssues(stories []Story, keys map[string][]int) []ReferenceIssue {
+	var issues []ReferenceIssue
+	for _, s := range stories {
+		if rows := keys[s.RefKey]; s.RefKey != "" && len(rows) > 1 {
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldRefKey,
+				Message: fmt.Sprintf("引用键 %s 重复（%s）", s.RefKey, rowList(rows))})
+		}
+	}
+	return issues
+}
+
+// rowList 格式化行号列表，如 "行2、行5"
+func rowList(rows []int) string {
+	parts := make([]string, len(rows))
+	for i, row := range rows {
+		parts[i] = fmt.Sprintf("行%d", row)
+	}
+	return strings.Join(parts, "、")
+}
+
+// ValidateReferences 校验 "@行号" 和 "@引用键" 父需求引用构成的关系图，返回全部问题（按行号排序）
+// 检查项：引用的行或引用键不存在、引用键重复、引用自身、引用成环、类型层级错误（父需求的类型层级不能低于子需求，如Story不能作为Epic的父需求；
+// 同类型的父子需求为子需求，如Story下的子Story）
+// 纯数字的禅道ID引用无法离线校验，不在检查范围内
+func ValidateReferences(stories []Story) []ReferenceIssue {
+	keys := IndexKeys(stories)
+	issues := duplicateKeyIssues(stories, keys)
+
+	byRow := make(map[int]*Story, len(stories))
+	for idx := range stories {
+		byRow[stories[idx].RowIndex] = &stories[idx]
+	}
+
+	parentOf := make(map[int]int) // 子需求行号 -> 父需求行号（仅包含有效引用）
+	for _, s := range stories {
+		row, isRowRef, err := ParseRowRef(s.ParentRef)
+		if key, isKeyRef := ParseKeyRef(s.ParentRef); isKeyRef {
+			row, err = keyRow(s.ParentRef, key, keys)
+		}
+		switch {
+		case !isRowRef:
+			continue
+		case err != nil:
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldParent, Message: err.Error()})
+		case row == s.RowIndex:
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldParent, Message: fmt.Sprintf("父需求引用 %s 指向自身", s.ParentRef)})
+		case byRow[row] == nil:
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldParent, Message: fmt.Sprintf("父需求引用 %s 指向的行不存在", s.ParentRef)})
+		default:
+			parentOf[s.RowIndex] = row
+		}
+	}
+
+	// 沿父需求链查找环，环上的每一行只报告一次
+	inCycle := make(map[int]bool)
+	state := make(map[int]int) // 0: 未访问, 1: 当前链上, 2: 已完成
+	for _, s := range stories {
+		var path []int
+		cur, hasNext := s.RowIndex, true
+		for hasNext && state[cur] == 0 {
+			state[cur] = 1
+			path = append(path, cur)
+			cur, hasNext = parentOf[cur]
+		}
+		if hasNext && state[cur] == 1 {
+			start := 0
+			for path[start] != cur {
+				start++
+			}
+			cycle := path[start:]
+			steps := make([]string, 0, len(cycle)+1)
+			minRow := cycle[0]
+			for _, row := range cycle {
+				inCycle[row] = true
+				steps = append(steps, fmt.Sprintf("行%d", row))
+				if row < minRow {
+					minRow = row
+				}
+			}
+			steps = append(steps, fmt.Sprintf("行%d", cycle[0]))
+			issues = append(issues, ReferenceIssue{RowIndex: minRow, Field: IssueFieldParent, Message: "父需求引用成环: " + strings.Join(steps, " → ")})
+		}
+		for _, row := range path {
+			state[row] = 2
+		}
+	}
+
+	for _, s := range stories {
+		parentRow, ok := parentOf[s.RowIndex]
+		if !ok || inCycle[s.RowIndex] {
+			continue
+		}
+		parent := byRow[parentRow]
+		if parent.Type.Level() > s.Type.Level() {
+			issues = append(issues, ReferenceIssue{RowIndex: s.RowIndex, Field: IssueFieldParent, Message: fmt.Sprintf("类型层级错误: %s 不能作为 %s 的父需求（父需求引用 %s）",
+				parent.GetTypeString(), s.GetTypeString(), s.ParentRef)})
+		}
+	}
+
+	sort.SliceStable(issues, func(a, b int) bool { return issues[a].RowIndex < issues[b].RowIndex })
+	return issues
+}
@@ -0,0 +1,147 @@
+package story
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		stories []Story
+		want    []string // 期望的问题（按顺序，包含的关键字）
+	}{
+		{
+			name: "合法的层级引用",
+			stories: []Story{
+				{Type: StoryTypeEpic, RowIndex: 1},
+				{Type: StoryTypeRequirement, RowIndex: 2, ParentRef: "@1"},
+				{Type: StoryTypeStory, RowIndex: 3, ParentRef: "@2"},
+				{Type: StoryTypeStory, RowIndex: 4, ParentRef: "100"},
+			},
+		},
+		{
+			name: "同类型的子需求",
+			stories: []Story{
+				{Type: StoryTypeRequirement, RowIndex: 1},
+				{Type: StoryTypeRequirement, RowIndex: 2, ParentRef: "@1"},
+				{Type: StoryTypeStory, RowIndex: 3, ParentRef: "@2"},
+				{Type: StoryTypeStory, RowIndex: 4, ParentRef: "@3"},
+			},
+		},
+		{
+			name: "引用不存在的行和自身",
+			stories: []Story{
+				{Type: StoryTypeStory, RowIndex: 1, ParentRef: "@9"},
+				{Type: StoryTypeStory, RowIndex: 2, ParentRef: "@2"},
+				{Type: StoryTypeStory, RowIndex: 3, ParentRef: "@x y"},
+			},
+			want: []string{"行1: 父需求引用 @9 指向的行不存在", "行2: 父需求引用 @2 指向自身", "行3: 无效的父需求引用格式"},
+		},
+		{
+			name: "引用成环",
+			stories: []Story{
+				{Type: StoryTypeStory, RowIndex: 1},
+				{Type: StoryTypeStory, RowIndex: 2, ParentRef: "@4"},
+				{Type: StoryTypeStory, RowIndex: 3, ParentRef: "@2"},
+				{Type: StoryTypeStory, RowIndex: 4, ParentRef: "@3"},
+				{Type: StoryTypeStory, RowIndex: 5, ParentRef: "@4"},
+			},
+			want: []string{"行2: 父需求引用成环: 行2 → 行4 → 行3 → 行2"},
+		},
+		{
+			name: "引用键",
+			stories: []Story{
+				{Type: StoryTypeEpic, RowIndex: 1, RefKey: "PAY-EPIC-1"},
+				{Type: StoryTypeRequirement, RowIndex: 2, RefKey: "PAY-REQ", ParentRef: "@PAY-EPIC-1"},
+				{Type: StoryTypeStory, RowIndex: 3, RefKey: "DUP", ParentRef: "@PAY-REQ"},
+				{Type: StoryTypeStory, RowIndex: 4, RefKey: "DUP", ParentRef: "@PAY-REQ"},
+				{Type: StoryTypeStory, RowIndex: 5, ParentRef: "@DUP"},
+				{Type: StoryTypeStory, RowIndex: 6, ParentRef: "@NONE"},
+				{Type: StoryTypeEpic, RowIndex: 7, RefKey: "SELF", ParentRef: "@SELF"},
+			},
+			want: []string{"行3: 引用键 DUP 重复（行3、行4）", "行4: 引用键 DUP 重复",
+				"行5: 父需求引用 @DUP 对应多行（行3、行4）", "行6: 父需求引用 @NONE 指向的引用键不存在", "行7: 父需求引用 @SELF 指向自身"},
+		},
+		{
+			name: "类型层级错误",
+			stories: []Story{
+				{Type: StoryTypeStory, RowIndex: 1},
+				{Type: StoryTypeEpic, RowIndex: 2, ParentRef: "@1"},
+				{Type: StoryTypeRequirement, RowIndex: 3, ParentRef: "@1"},
+				{Type: StoryTypeEpic, RowIndex: 4, ParentRef: "@3"},
+			},
+			want: []string{"行2: 类型层级错误: 研发需求(Story) 不能作为 业务需求(Epic)", "行3: 类型层级错误", "行4: 类型层级错误"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := ValidateReferences(tt.stories)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("期望 %d 个问题, 得到 %d: %v", len(tt.want), len(issues), issues)
+			}
+			for idx, want := range tt.want {
+				if got := issues[idx].String(); !strings.HasPrefix(got, want) {
+					t.Errorf("问题 #%d = %q, want prefix %q", idx+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveKeyRefs(t *testing.T) {
+	stories := []Story{
+		{RowIndex: 1, ParentRef: "@PAY-REQ"},
+		{RowIndex: 2, RefKey: "PAY-REQ"},
+		{RowIndex: 3, ParentRef: "@2"},
+		{RowIndex: 4, ParentRef: "@NONE"},
+		{RowIndex: 5, ParentRef: "100"},
+	}
+	issues := ResolveKeyRefs(stories)
+
+	if stories[0].ParentRef != "@2" || stories[0].ParentKey != "PAY-REQ" {
+		t.Errorf("行1 引用键应改写为 @2, 得到 %q (ParentKey %q)", stories[0].ParentRef, stories[0].ParentKey)
+	}
+	if stories[2].ParentRef != "@2" || stories[4].ParentRef != "100" {
+		t.Errorf("行号引用和禅道ID引用不应改变: %q, %q", stories[2].ParentRef, stories[4].ParentRef)
+	}
+	if len(issues) != 1 || issues[0].RowIndex != 4 || issues[0].Field != IssueFieldParent || stories[3].ParentRef != "@NONE" {
+		t.Errorf("期望行4在父需求字段报告引用键不存在且保持原值, 得到 %+v", issues)
+	}
+
+	// 重复的引用键报告在引用键字段
+	issues = ResolveKeyRefs([]Story{{RowIndex: 1, RefKey: "DUP"}, {RowIndex: 2, RefKey: "DUP"}})
+	if len(issues) != 2 || issues[0].Field != IssueFieldRefKey || issues[1].Field != IssueFieldRefKey {
+		t.Errorf("期望两行在引用键字段报告重复, 得到 %+v", issues)
+	}
+}
+
+func TestValidateRefKey(t *testing.T) {
+	for key, valid := range map[string]bool{"PAY-EPIC-1": true, "需求_1": true, "12": false, "a b": false, "a!1": false, "@a": false} {
+		if err := ValidateRefKey(key); (err == nil) != valid {
+			t.Errorf("ValidateRefKey(%q) = %v, want valid=%v", key, err, valid)
+		}
+	}
+}
+
+func TestParseTitleRef(t *testing.T) {
+	tests := []struct {
+		ref        string
+		wantTitle  string
+		isTitleRef bool
+		wantErr    bool
+	}{
+		{ref: "#title:会员体系", wantTitle: "会员体系", isTitleRef: true},
+		{ref: "#Title： 会员体系 ", wantTitle: "会员体系", isTitleRef: true},
+		{ref: "#title:", isTitleRef: true, wantErr: true},
+		{ref: "@1"},
+		{ref: "123"},
+	}
+	for _, tt := range tests {
+		title, isTitleRef, err := ParseTitleRef(tt.ref)
+		if title != tt.wantTitle || isTitleRef != tt.isTitleRef || (err != nil) != tt.wantErr {
+			t.Errorf("ParseTitleRef(%q) = %q, %v, %v", tt.ref, title, isTitleRef, err)
+		}
+	}
+}
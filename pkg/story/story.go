@@ -1,6 +1,8 @@
 // Package story 定义需求领域模型
 package story
 
+import "strings"
+
 // StoryType 需求类型
 type StoryType string
 
@@ -10,23 +12,53 @@ const (
 	StoryTypeStory       StoryType = "story"       // 研发需求
 )
 
+// Level 返回需求类型的层级（Epic=1, Requirement=2, Story=3），父需求的层级不能大于子需求（同类型时为子需求）
+func (t StoryType) Level() int {
+	switch t {
+	case StoryTypeEpic:
+		return 1
+	case StoryTypeRequirement:
+		return 2
+	default:
+		return 3
+	}
+}
+
 // Story 表示需求数据模型
 type Story struct {
-	Type       StoryType // 需求类型：epic/requirement/story
-	Title      string    // 标题*
-	ProductID  int       // 产品ID*
-	Priority   int       // 优先级* (1-4)
-	Category   string    // 分类*
-	Spec       string    // 需求描述
-	ParentID   int       // 父需求ID（实际禅道ID，导入时解析填充）
-	ParentRef  string    // 父需求引用（原始值，如 "@1" 或 "123"）
-	Source     string    // 来源
-	SourceNote string    // 来源备注
-	Estimate   float64   // 预计工时
-	Keywords   string    // 关键词
-	Verify     string    // 验收标准
-	Module     int       // 模块ID（-1表示Excel未填写需使用配置默认值，>=0为Excel显式指定，0也是合法值表示不归属具体模块）
-	RowIndex   int       // 行号（Excel数据行号，1-based，用于层级引用）
+	Type         StoryType // 需求类型：epic/requirement/story
+	Title        string    // 标题*
+	ProductID    int       // 产品ID*
+	ProductRef   string    // 产品名称或代号（Excel产品列未填写数字时，导入前解析为ProductID）
+	Priority     int       // 优先级* (1-4)
+	Category     string    // 分类*
+	Spec         string    // 需求描述
+	ParentID     int       // 父需求ID（实际禅道ID，导入时解析填充）
+	ParentRef    string    // 父需求引用（原始值，如 "@1"、"@PAY-EPIC-1" 或 "123"；"@引用键" 读取后改写为 "@行号"）
+	ParentKey    string    // 父需求引用键（ParentRef 原为 "@引用键" 时保存引用键，用于日志显示）
+	ParentTitle  string    // 父需求标题（ParentRef 为 "#title:标题" 时，导入前在产品已有的业务需求/用户需求中查找并写入ParentID）
+	RefKey       string    // 引用键（可选，其他行可用 "@引用键" 引用本行作为父需求，插入或排序行后引用不变）
+	Source       string    // 来源
+	SourceNote   string    // 来源备注
+	Estimate     float64   // 预计工时
+	Keywords     string    // 关键词
+	Verify       string    // 验收标准
+	Module       int       // 模块ID（-1表示Excel未填写需使用配置默认值，>=0为Excel显式指定，0也是合法值表示不归属具体模块）
+	ModulePath   string    // 模块路径（Excel模块列填写路径时，如 "支付/退款/对账"，导入前按产品模块树解析为Module）
+	Grade        int       // 层级（同类型父需求链的深度，1为顶级，对应禅道需求的grade；0表示尚未计算）
+	RowIndex     int       // 行号（Excel数据行号，1-based，用于层级引用；读取多个工作表时为合并后的序号）
+	Sheet        string    // 所在工作表名称
+	SheetRow     int       // 所在工作表中的行号（标题行之前的行也计入，用于回写结果）
+	AssignedTo   string    // 指派给（账号，可选，为空时使用配置默认值）
+	Reviewers    []string  // 评审人（账号，可选，可填写多个，为空时使用配置默认评审人）
+	Project      int       // 所属项目ID（可选，仅研发需求）
+	ProjectRef   string    // 项目名称（Excel项目列未填写数字时，导入前解析为Project）
+	Execution    int       // 所属执行（迭代）ID（可选，仅研发需求）
+	ExecutionRef string    // 执行名称（Excel执行列未填写数字时，导入前解析为Execution）
+	Plan         int       // 所属产品计划ID（可选，用户需求和研发需求）
+	PlanRef      string    // 计划名称（Excel计划列未填写数字时，导入前解析为Plan）
+	ExternalID   string    // 外部ID（可选，幂等导入时用于匹配已导入的需求）
+	ZentaoID     int       // 禅道ID（可选，回写列或导出文件中已存在的禅道需求ID，比对时优先按ID匹配）
 }
 
 // GetTypeString 获取需求类型的字符串表示
@@ -42,3 +74,14 @@ func (s *Story) GetTypeString() string {
 		return "研发需求(Story)"
 	}
 }
+
+// NormalizeModulePath 规范化模块路径：按 "/" 拆分并去除各段首尾空白和空段，如 " 支付 / 退款/" → "支付/退款"
+func NormalizeModulePath(path string) string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg = strings.TrimSpace(seg); seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return strings.Join(segments, "/")
+}